@@ -0,0 +1,163 @@
+// Package acl decides which tube operations a client may perform, based on its authenticated
+// principal and source address.
+package acl
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"slices"
+)
+
+var ErrInvalidRule = errors.New("invalid rule")
+
+type Action string
+
+const (
+	ActionUse     Action = "use"
+	ActionPut     Action = "put"
+	ActionWatch   Action = "watch"
+	ActionReserve Action = "reserve"
+	ActionDelete  Action = "delete"
+	ActionKick    Action = "kick"
+	ActionPause   Action = "pause"
+	// ActionAdmin covers client management and is checked against an empty tube. It is only
+	// allowed by rules explicitly listing it, never by rules without actions or DefaultAllow.
+	ActionAdmin Action = "admin"
+)
+
+// Actions lists all actions that can be controlled.
+var Actions = []Action{
+	ActionUse,
+	ActionPut,
+	ActionWatch,
+	ActionReserve,
+	ActionDelete,
+	ActionKick,
+	ActionPause,
+	ActionAdmin,
+}
+
+// Request describes an operation to be checked. Principal is empty for unauthenticated clients,
+// and Source is invalid for clients not connected over IP.
+type Request struct {
+	Principal string
+	Source    netip.Addr
+	Action    Action
+	Tube      string
+}
+
+// Rule matches requests where every non-empty condition holds. Principals and Tubes are glob
+// patterns, where '*' matches any sequence of characters and '?' matches a single character. As
+// unauthenticated clients have an empty principal, "*" matches them too, whereas "?*" only matches
+// authenticated clients.
+type Rule struct {
+	Allow      bool
+	Principals []string
+	Sources    []netip.Prefix
+	Actions    []Action
+	Tubes      []string
+}
+
+func (r *Rule) Validate() error {
+	for _, a := range r.Actions {
+		if !slices.Contains(Actions, a) {
+			return fmt.Errorf("%w: unknown action %q", ErrInvalidRule, a)
+		}
+	}
+
+	return nil
+}
+
+// Matches reports whether all conditions of the rule hold for the request.
+func (r *Rule) Matches(req Request) bool {
+	if len(r.Actions) > 0 && !slices.Contains(r.Actions, req.Action) {
+		return false
+	}
+
+	if len(r.Principals) > 0 && !matchAny(r.Principals, req.Principal) {
+		return false
+	}
+
+	if len(r.Tubes) > 0 && !matchAny(r.Tubes, req.Tube) {
+		return false
+	}
+
+	if len(r.Sources) > 0 {
+		if !req.Source.IsValid() {
+			return false
+		}
+
+		return slices.ContainsFunc(r.Sources, func(p netip.Prefix) bool {
+			return p.Contains(req.Source)
+		})
+	}
+
+	return true
+}
+
+// Policy is an ordered list of rules, the first matching rule decides whether a request is
+// allowed. Requests matching no rule are allowed only if DefaultAllow is set, and admin requests
+// are never allowed by default.
+type Policy struct {
+	Rules        []Rule
+	DefaultAllow bool
+}
+
+// Allowed reports whether the request is permitted.
+func (p *Policy) Allowed(req Request) bool {
+	for i := range p.Rules {
+		if req.Action == ActionAdmin && !slices.Contains(p.Rules[i].Actions, ActionAdmin) {
+			continue
+		}
+
+		if p.Rules[i].Matches(req) {
+			return p.Rules[i].Allow
+		}
+	}
+
+	return p.DefaultAllow && req.Action != ActionAdmin
+}
+
+func matchAny(patterns []string, s string) bool {
+	return slices.ContainsFunc(patterns, func(p string) bool {
+		return Match(p, s)
+	})
+}
+
+// Match reports whether s matches the glob pattern.
+func Match(pattern string, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			pattern = pattern[1:]
+
+			if len(pattern) == 0 {
+				return true
+			}
+
+			for i := 0; i <= len(s); i++ {
+				if Match(pattern, s[i:]) {
+					return true
+				}
+			}
+
+			return false
+
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+
+		default:
+			if len(s) == 0 || s[0] != pattern[0] {
+				return false
+			}
+		}
+
+		pattern = pattern[1:]
+		s = s[1:]
+	}
+
+	return len(s) == 0
+}
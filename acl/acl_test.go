@@ -0,0 +1,77 @@
+package acl_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/csnewman/beanbridge/acl"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatch(t *testing.T) {
+	t.Parallel()
+
+	require.True(t, acl.Match("emails", "emails"))
+	require.False(t, acl.Match("emails", "emails-replies"))
+	require.True(t, acl.Match("emails*", "emails-replies"))
+	require.True(t, acl.Match("*", ""))
+	require.False(t, acl.Match("?*", ""), "?* should only match authenticated principals")
+	require.True(t, acl.Match("?*", "alice"))
+	require.True(t, acl.Match("a/*/c", "a/b/b/c"))
+	require.True(t, acl.Match("job-?", "job-1"))
+	require.False(t, acl.Match("job-?", "job-"))
+}
+
+func TestPolicy(t *testing.T) {
+	t.Parallel()
+
+	p := &acl.Policy{
+		Rules: []acl.Rule{
+			{
+				Allow:      true,
+				Principals: []string{"service-a"},
+				Actions:    []acl.Action{acl.ActionUse, acl.ActionPut},
+				Tubes:      []string{"emails"},
+			},
+			{
+				Allow:      true,
+				Principals: []string{"service-a"},
+				Actions:    []acl.Action{acl.ActionWatch, acl.ActionReserve, acl.ActionDelete},
+				Tubes:      []string{"emails-replies"},
+			},
+			{
+				Allow:   true,
+				Sources: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")},
+			},
+		},
+	}
+
+	local := netip.MustParseAddr("192.168.1.1")
+	internal := netip.MustParseAddr("10.1.2.3")
+
+	cases := []struct {
+		req     acl.Request
+		allowed bool
+	}{
+		{acl.Request{Principal: "service-a", Source: local, Action: acl.ActionPut, Tube: "emails"}, true},
+		{acl.Request{Principal: "service-a", Source: local, Action: acl.ActionPut, Tube: "emails-replies"}, false},
+		{acl.Request{Principal: "service-a", Source: local, Action: acl.ActionReserve, Tube: "emails-replies"}, true},
+		{acl.Request{Principal: "service-a", Source: local, Action: acl.ActionReserve, Tube: "emails"}, false},
+		{acl.Request{Principal: "service-b", Source: local, Action: acl.ActionPut, Tube: "emails"}, false},
+		{acl.Request{Principal: "service-b", Source: internal, Action: acl.ActionKick, Tube: "other"}, true},
+		{acl.Request{Action: acl.ActionPut, Tube: "emails"}, false},
+		{acl.Request{Principal: "service-b", Source: internal, Action: acl.ActionAdmin}, false},
+	}
+
+	for _, c := range cases {
+		require.Equal(t, c.allowed, p.Allowed(c.req), "%+v", c.req)
+	}
+
+	open := &acl.Policy{DefaultAllow: true}
+	require.True(t, open.Allowed(acl.Request{Action: acl.ActionPut, Tube: "emails"}))
+	require.False(t, open.Allowed(acl.Request{Action: acl.ActionAdmin}), "Admin should not be allowed by default")
+
+	open.Rules = []acl.Rule{{Allow: true, Principals: []string{"ops"}, Actions: []acl.Action{acl.ActionAdmin}}}
+	require.True(t, open.Allowed(acl.Request{Principal: "ops", Action: acl.ActionAdmin}))
+	require.False(t, open.Allowed(acl.Request{Principal: "dev", Action: acl.ActionAdmin}))
+}
@@ -0,0 +1,610 @@
+// Package admin provides an HTTP/JSON API for inspecting and managing tubes and jobs.
+package admin
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/csnewman/beanbridge/audit"
+	"github.com/csnewman/beanbridge/backend"
+	"github.com/csnewman/beanbridge/beanstalk"
+	"github.com/csnewman/beanbridge/events"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 1000
+)
+
+var errBadRequest = errors.New("bad request")
+
+// Bridge is the part of the bridge managed through the API.
+type Bridge interface {
+	Backend() backend.Backend
+
+	SetDraining(draining bool)
+
+	Draining() bool
+
+	Clients() []beanstalk.ClientInfo
+
+	// Disconnect closes the connection of a client, returning beanstalk.ErrNotFound if there is no
+	// such client.
+	Disconnect(id uint64) error
+
+	// Audit records a job lifecycle event caused through the API.
+	Audit(e audit.Event)
+
+	Events() *events.Bus
+}
+
+// auditPrincipal identifies operations made through the API in the audit log.
+const auditPrincipal = "admin-api"
+
+// Handler serves the admin API.
+type Handler struct {
+	logger *slog.Logger
+	bridge Bridge
+	token  string
+	mux    *http.ServeMux
+	debug  bool
+	// blockRate is tracked as the runtime does not expose the current block profile rate
+	blockRate atomic.Int64
+}
+
+// NewHandler creates the API handler. If token is set, requests must present it as a bearer token.
+func NewHandler(logger *slog.Logger, bridge Bridge, token string, opts ...Option) *Handler {
+	h := &Handler{
+		logger: logger,
+		bridge: bridge,
+		token:  token,
+		mux:    http.NewServeMux(),
+	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	h.mux.HandleFunc("GET /api/tubes", h.listTubes)
+	h.mux.HandleFunc("GET /api/tubes/{tube}", h.getTube)
+	h.mux.HandleFunc("GET /api/tubes/{tube}/jobs", h.listJobs)
+	h.mux.HandleFunc("POST /api/tubes/{tube}/pause", h.pauseTube)
+	h.mux.HandleFunc("POST /api/tubes/{tube}/kick", h.kickTube)
+	h.mux.HandleFunc("GET /api/jobs/{id}", h.getJob)
+	h.mux.HandleFunc("GET /api/jobs/{id}/body", h.getJobBody)
+	h.mux.HandleFunc("DELETE /api/jobs/{id}", h.deleteJob)
+	h.mux.HandleFunc("POST /api/jobs/{id}/kick", h.kickJob)
+	h.mux.HandleFunc("POST /api/jobs/{id}/bury", h.buryJob)
+	h.mux.HandleFunc("GET /api/drain", h.getDrain)
+	h.mux.HandleFunc("PUT /api/drain", h.setDrain)
+	h.mux.HandleFunc("GET /api/clients", h.listClients)
+	h.mux.HandleFunc("DELETE /api/clients/{id}", h.disconnectClient)
+	h.mux.HandleFunc("GET /api/events", h.streamEvents)
+	h.mux.Handle("GET /", dashboard())
+
+	if h.debug {
+		h.registerDebug()
+	}
+
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// The dashboard assets are public, the dashboard prompts for the token used to call the api
+	if h.token != "" && isProtected(r.URL.Path) && !h.authorized(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+
+		return
+	}
+
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *Handler) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+	// EventSource and go tool pprof cannot set headers, so the event stream and debug endpoints
+	// also accept the token as a parameter
+	if !ok && (r.URL.Path == "/api/events" || strings.HasPrefix(r.URL.Path, "/debug/")) {
+		token = r.URL.Query().Get("access_token")
+		ok = token != ""
+	}
+
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) == 1
+}
+
+func isProtected(path string) bool {
+	return strings.HasPrefix(path, "/api/") || strings.HasPrefix(path, "/debug/")
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+type tubeResponse struct {
+	Name          string `json:"name"`
+	Ready         uint64 `json:"ready"`
+	Delayed       uint64 `json:"delayed"`
+	Reserved      uint64 `json:"reserved"`
+	Buried        uint64 `json:"buried"`
+	Waiting       uint64 `json:"waiting"`
+	Paused        bool   `json:"paused"`
+	TotalPuts     uint64 `json:"total_puts"`
+	TotalReserves uint64 `json:"total_reserves"`
+	TotalTimeouts uint64 `json:"total_timeouts"`
+	TotalDeletes  uint64 `json:"total_deletes"`
+}
+
+func newTubeResponse(t backend.TubeStats) tubeResponse {
+	return tubeResponse{
+		Name:          t.Name,
+		Ready:         t.Ready,
+		Delayed:       t.Delayed,
+		Reserved:      t.Reserved,
+		Buried:        t.Buried,
+		Waiting:       t.Waiting,
+		Paused:        t.Paused,
+		TotalPuts:     t.TotalPuts,
+		TotalReserves: t.TotalReserves,
+		TotalTimeouts: t.TotalTimeouts,
+		TotalDeletes:  t.TotalDeletes,
+	}
+}
+
+type jobResponse struct {
+	ID       uint64 `json:"id"`
+	Tube     string `json:"tube"`
+	State    string `json:"state"`
+	Priority uint64 `json:"priority"`
+	TTR      uint64 `json:"ttr"`
+	Size     int    `json:"size"`
+	// Body is set for UTF-8 bodies, otherwise BodyBase64 holds the encoded body.
+	Body       *string `json:"body,omitempty"`
+	BodyBase64 []byte  `json:"body_base64,omitempty"`
+}
+
+func newJobResponse(j *backend.Job, withBody bool) jobResponse {
+	res := jobResponse{
+		ID:       j.ID,
+		Tube:     j.Tube,
+		State:    string(j.State),
+		Priority: j.Priority,
+		TTR:      j.TTR,
+		Size:     len(j.Data),
+	}
+
+	if !withBody {
+		return res
+	}
+
+	if utf8.Valid(j.Data) {
+		body := string(j.Data)
+		res.Body = &body
+	} else {
+		res.BodyBase64 = j.Data
+	}
+
+	return res
+}
+
+type clientResponse struct {
+	ID             uint64     `json:"id"`
+	Address        string     `json:"address"`
+	Principal      string     `json:"principal,omitempty"`
+	Using          string     `json:"using"`
+	Watching       []string   `json:"watching"`
+	Reserved       []uint64   `json:"reserved"`
+	Command        string     `json:"command,omitempty"`
+	CommandStarted *time.Time `json:"command_started,omitempty"`
+	BytesIn        uint64     `json:"bytes_in"`
+	BytesOut       uint64     `json:"bytes_out"`
+	ConnectedAt    time.Time  `json:"connected_at"`
+}
+
+func newClientResponse(c beanstalk.ClientInfo) clientResponse {
+	res := clientResponse{
+		ID:          c.ID,
+		Address:     c.Address,
+		Principal:   c.Principal,
+		Using:       c.Using,
+		Watching:    c.Watching,
+		Reserved:    c.Reserved,
+		Command:     c.Command,
+		BytesIn:     c.BytesIn,
+		BytesOut:    c.BytesOut,
+		ConnectedAt: c.ConnectedAt,
+	}
+
+	if c.Command != "" {
+		res.CommandStarted = &c.CommandStarted
+	}
+
+	return res
+}
+
+type jobsResponse struct {
+	Jobs []jobResponse `json:"jobs"`
+	// Next is the cursor for the following page, omitted on the last page.
+	Next uint64 `json:"next,omitempty"`
+}
+
+func (h *Handler) listTubes(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.bridge.Backend().TubeStats(r.Context())
+	if err != nil {
+		h.writeError(w, err)
+
+		return
+	}
+
+	tubes := make([]tubeResponse, 0, len(stats))
+
+	for _, t := range stats {
+		tubes = append(tubes, newTubeResponse(t))
+	}
+
+	writeJSON(w, http.StatusOK, tubes)
+}
+
+func (h *Handler) getTube(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.bridge.Backend().TubeStats(r.Context())
+	if err != nil {
+		h.writeError(w, err)
+
+		return
+	}
+
+	name := r.PathValue("tube")
+
+	for _, t := range stats {
+		if t.Name == name {
+			writeJSON(w, http.StatusOK, newTubeResponse(t))
+
+			return
+		}
+	}
+
+	h.writeError(w, beanstalk.ErrNotFound)
+}
+
+func (h *Handler) listJobs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	state := backend.JobState(query.Get("state"))
+	if state == "" {
+		state = backend.JobReady
+	}
+
+	switch state {
+	case backend.JobReady, backend.JobDelayed, backend.JobReserved, backend.JobBuried:
+	default:
+		h.writeError(w, errBadRequest)
+
+		return
+	}
+
+	after, err := queryUint(query.Get("after"), 0)
+	if err != nil {
+		h.writeError(w, err)
+
+		return
+	}
+
+	// Negative limits fail to parse, and a limit of zero selects the default page size
+	limit, err := queryUint(query.Get("limit"), defaultPageSize)
+	if err != nil {
+		h.writeError(w, err)
+
+		return
+	}
+
+	if limit == 0 {
+		limit = defaultPageSize
+	}
+
+	limit = min(limit, maxPageSize)
+
+	jobs, err := h.bridge.Backend().ListJobs(r.Context(), r.PathValue("tube"), state, after, int(limit))
+	if err != nil {
+		h.writeError(w, err)
+
+		return
+	}
+
+	res := jobsResponse{
+		Jobs: make([]jobResponse, 0, len(jobs)),
+	}
+
+	for _, j := range jobs {
+		res.Jobs = append(res.Jobs, newJobResponse(j, false))
+	}
+
+	if len(jobs) == int(limit) {
+		res.Next = jobs[len(jobs)-1].ID
+	}
+
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (h *Handler) pauseTube(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Delay uint64 `json:"delay"`
+	}
+
+	if err := readJSON(r, &req); err != nil {
+		h.writeError(w, err)
+
+		return
+	}
+
+	b := h.bridge.Backend()
+	tube := b.ResolveTube(r.PathValue("tube"))
+
+	defer tube.Release()
+
+	if err := b.PauseTube(r.Context(), tube, req.Delay); err != nil {
+		h.writeError(w, err)
+
+		return
+	}
+
+	h.logger.Info("Paused tube via admin api", "tube", tube.Name(), "delay", req.Delay)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) kickTube(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Bound uint64 `json:"bound"`
+	}
+
+	if err := readJSON(r, &req); err != nil {
+		h.writeError(w, err)
+
+		return
+	}
+
+	b := h.bridge.Backend()
+	tube := b.ResolveTube(r.PathValue("tube"))
+
+	defer tube.Release()
+
+	kicked, err := b.Kick(r.Context(), tube, req.Bound)
+	if err != nil {
+		h.writeError(w, err)
+
+		return
+	}
+
+	h.logger.Info("Kicked jobs via admin api", "tube", tube.Name(), "kicked", kicked)
+
+	if kicked > 0 {
+		h.audit(r, audit.Event{Action: audit.ActionKick, Count: kicked, Tube: tube.Name()})
+	}
+
+	writeJSON(w, http.StatusOK, map[string]uint64{"kicked": kicked})
+}
+
+func (h *Handler) getJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.peek(w, r)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newJobResponse(job, true))
+}
+
+func (h *Handler) getJobBody(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.peek(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.WriteHeader(http.StatusOK)
+
+	_, _ = w.Write(job.Data)
+}
+
+func (h *Handler) deleteJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.peek(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.bridge.Backend().Delete(r.Context(), job.ID); err != nil {
+		h.writeError(w, err)
+
+		return
+	}
+
+	h.logger.Info("Deleted job via admin api", "id", job.ID)
+
+	h.audit(r, audit.Event{Action: audit.ActionDelete, JobID: job.ID, Tube: job.Tube})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) kickJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.peek(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.bridge.Backend().KickJob(r.Context(), job.ID); err != nil {
+		h.writeError(w, err)
+
+		return
+	}
+
+	h.logger.Info("Kicked job via admin api", "id", job.ID)
+
+	h.audit(r, audit.Event{Action: audit.ActionKick, JobID: job.ID, Tube: job.Tube})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// buryJob buries a ready or delayed job. Jobs reserved by a client can only be buried by that
+// client.
+func (h *Handler) buryJob(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.pathID(w, r)
+	if !ok {
+		return
+	}
+
+	b := h.bridge.Backend()
+
+	job, err := b.Peek(r.Context(), id)
+	if err != nil {
+		h.writeError(w, err)
+
+		return
+	}
+
+	if err := b.BuryJob(r.Context(), id); err != nil {
+		h.writeError(w, err)
+
+		return
+	}
+
+	h.logger.Info("Buried job via admin api", "id", id)
+
+	h.audit(r, audit.Event{Action: audit.ActionBury, JobID: id, Tube: job.Tube})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type drainRequest struct {
+	Draining bool `json:"draining"`
+}
+
+func (h *Handler) getDrain(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, drainRequest{Draining: h.bridge.Draining()})
+}
+
+func (h *Handler) setDrain(w http.ResponseWriter, r *http.Request) {
+	var req drainRequest
+
+	if err := readJSON(r, &req); err != nil {
+		h.writeError(w, err)
+
+		return
+	}
+
+	h.bridge.SetDraining(req.Draining)
+
+	writeJSON(w, http.StatusOK, drainRequest{Draining: h.bridge.Draining()})
+}
+
+func (h *Handler) listClients(w http.ResponseWriter, _ *http.Request) {
+	clients := h.bridge.Clients()
+	res := make([]clientResponse, 0, len(clients))
+
+	for _, c := range clients {
+		res = append(res, newClientResponse(c))
+	}
+
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (h *Handler) disconnectClient(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.pathID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.bridge.Disconnect(id); err != nil {
+		h.writeError(w, err)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// audit records an operation made through the API, attributed to the address of the caller.
+func (h *Handler) audit(r *http.Request, e audit.Event) {
+	e.Remote = r.RemoteAddr
+	e.Principal = auditPrincipal
+
+	h.bridge.Audit(e)
+}
+
+func (h *Handler) pathID(w http.ResponseWriter, r *http.Request) (uint64, bool) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		h.writeError(w, errBadRequest)
+
+		return 0, false
+	}
+
+	return id, true
+}
+
+func (h *Handler) peek(w http.ResponseWriter, r *http.Request) (*backend.Job, bool) {
+	id, ok := h.pathID(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	job, err := h.bridge.Backend().Peek(r.Context(), id)
+	if err != nil {
+		h.writeError(w, err)
+
+		return nil, false
+	}
+
+	return job, true
+}
+
+func (h *Handler) writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errBadRequest):
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+	case errors.Is(err, beanstalk.ErrNotFound):
+		writeJSON(w, http.StatusNotFound, errorResponse{Error: "not found"})
+	default:
+		h.logger.Error("Admin request failed", "err", err)
+
+		writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "internal error"})
+	}
+}
+
+func readJSON(r *http.Request, v any) error {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(v); err != nil {
+		return errors.Join(errBadRequest, err)
+	}
+
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func queryUint(raw string, def uint64) (uint64, error) {
+	if raw == "" {
+		return def, nil
+	}
+
+	v, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil {
+		return 0, errBadRequest
+	}
+
+	return v, nil
+}
@@ -0,0 +1,287 @@
+package admin_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"runtime/pprof"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/csnewman/beanbridge/admin"
+	"github.com/csnewman/beanbridge/audit"
+	"github.com/csnewman/beanbridge/backend"
+	"github.com/csnewman/beanbridge/backend/memory"
+	"github.com/csnewman/beanbridge/beanstalk"
+	"github.com/csnewman/beanbridge/events"
+	"github.com/stretchr/testify/require"
+)
+
+type testBridge struct {
+	backend  backend.Backend
+	events   *events.Bus
+	draining atomic.Bool
+}
+
+func (b *testBridge) Backend() backend.Backend {
+	return b.backend
+}
+
+func (b *testBridge) SetDraining(draining bool) {
+	b.draining.Store(draining)
+}
+
+func (b *testBridge) Draining() bool {
+	return b.draining.Load()
+}
+
+func (b *testBridge) Clients() []beanstalk.ClientInfo {
+	return []beanstalk.ClientInfo{
+		{
+			ID:       1,
+			Address:  "127.0.0.1:5000",
+			Using:    "emails",
+			Watching: []string{"default"},
+			Command:  "reserve",
+		},
+	}
+}
+
+func (b *testBridge) Audit(_ audit.Event) {}
+
+func (b *testBridge) Events() *events.Bus {
+	return b.events
+}
+
+func (b *testBridge) Disconnect(id uint64) error {
+	if id != 1 {
+		return beanstalk.ErrNotFound
+	}
+
+	return nil
+}
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	b := memory.NewBackend(slog.Default(), &memory.Config{})
+
+	t.Cleanup(func() {
+		_ = b.(io.Closer).Close()
+	})
+
+	tube := b.ResolveTube("emails")
+	defer tube.Release()
+
+	var ids []uint64
+
+	for _, body := range []string{"first", "second", "third"} {
+		id, _, err := b.Put(ctx, tube, 10, 0, 60, []byte(body))
+		require.NoError(t, err)
+
+		ids = append(ids, id)
+	}
+
+	bus := events.NewBus()
+	b.(backend.EventPublisher).PublishEvents(bus)
+
+	srv := httptest.NewServer(admin.NewHandler(slog.Default(), &testBridge{backend: b, events: bus}, "secret"))
+	t.Cleanup(srv.Close)
+
+	send := func(method string, path string, body string, out any) int {
+		req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+
+		req.Header.Set("Authorization", "Bearer secret")
+
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+
+		defer res.Body.Close()
+
+		if out != nil {
+			require.NoError(t, json.NewDecoder(res.Body).Decode(out))
+		}
+
+		return res.StatusCode
+	}
+
+	res, err := http.Get(srv.URL + "/api/tubes")
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+	require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+	res, err = http.Get(srv.URL + "/")
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	var clients []map[string]any
+	require.Equal(t, http.StatusOK, send(http.MethodGet, "/api/clients", "", &clients))
+	require.Len(t, clients, 1)
+	require.Equal(t, "emails", clients[0]["using"])
+	require.Equal(t, "reserve", clients[0]["command"])
+	require.Equal(t, http.StatusNoContent, send(http.MethodDelete, "/api/clients/1", "", nil))
+	require.Equal(t, http.StatusNotFound, send(http.MethodDelete, "/api/clients/2", "", nil))
+
+	var tubes []map[string]any
+	require.Equal(t, http.StatusOK, send(http.MethodGet, "/api/tubes", "", &tubes))
+	require.Len(t, tubes, 1)
+	require.Equal(t, "emails", tubes[0]["name"])
+	require.InDelta(t, 3, tubes[0]["ready"], 0)
+
+	var page struct {
+		Jobs []struct {
+			ID uint64 `json:"id"`
+		} `json:"jobs"`
+		Next uint64 `json:"next"`
+	}
+
+	require.Equal(t, http.StatusOK, send(http.MethodGet, "/api/tubes/emails/jobs?limit=2", "", &page))
+	require.Len(t, page.Jobs, 2)
+	require.Equal(t, ids[1], page.Next)
+
+	page.Next = 0
+	require.Equal(t, http.StatusOK, send(http.MethodGet, "/api/tubes/emails/jobs?limit=2&after=2", "", &page))
+	require.Len(t, page.Jobs, 1)
+	require.Equal(t, ids[2], page.Jobs[0].ID)
+	require.Zero(t, page.Next)
+
+	require.Equal(t, http.StatusBadRequest, send(http.MethodGet, "/api/tubes/emails/jobs?state=lost", "", nil))
+	require.Equal(t, http.StatusBadRequest, send(http.MethodGet, "/api/tubes/emails/jobs?limit=-1", "", nil))
+
+	page.Next = 0
+	require.Equal(t, http.StatusOK, send(http.MethodGet, "/api/tubes/emails/jobs?limit=0", "", &page))
+	require.Len(t, page.Jobs, 3, "Zero should select the default limit")
+
+	var job map[string]any
+	require.Equal(t, http.StatusOK, send(http.MethodGet, "/api/jobs/1", "", &job))
+	require.Equal(t, "first", job["body"])
+	require.Equal(t, "ready", job["state"])
+
+	var before, after map[string]any
+	require.Equal(t, http.StatusOK, send(http.MethodGet, "/api/tubes/emails", "", &before))
+	require.Contains(t, before, "total_reserves")
+
+	require.Equal(t, http.StatusNoContent, send(http.MethodPost, "/api/jobs/1/bury", "", nil))
+	require.Equal(t, http.StatusNotFound, send(http.MethodPost, "/api/jobs/1/bury", "", nil), "Buried jobs cannot be buried")
+	require.Equal(t, http.StatusOK, send(http.MethodGet, "/api/tubes/emails/jobs?state=buried", "", &page))
+	require.Len(t, page.Jobs, 1)
+
+	require.Equal(t, http.StatusOK, send(http.MethodGet, "/api/tubes/emails", "", &after))
+	require.Equal(t, before["total_reserves"], after["total_reserves"], "Burying should not reserve the job")
+
+	var kicked map[string]uint64
+	require.Equal(t, http.StatusOK, send(http.MethodPost, "/api/tubes/emails/kick", `{"bound": 10}`, &kicked))
+	require.Equal(t, uint64(1), kicked["kicked"])
+
+	res, err = http.Get(srv.URL + "/api/events?type=deleted&access_token=secret")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusNoContent, send(http.MethodDelete, "/api/jobs/2", "", nil))
+
+	stream := bufio.NewReader(res.Body)
+
+	line, err := stream.ReadString('\n')
+	require.NoError(t, err)
+	require.Equal(t, "event: deleted\n", line)
+
+	line, err = stream.ReadString('\n')
+	require.NoError(t, err)
+	require.Contains(t, line, `"job_id":2`)
+	require.Equal(t, http.StatusNotFound, send(http.MethodGet, "/api/jobs/2", "", nil))
+
+	var drain map[string]bool
+	require.Equal(t, http.StatusOK, send(http.MethodPut, "/api/drain", `{"draining": true}`, &drain))
+	require.True(t, drain["draining"])
+}
+
+func TestDebug(t *testing.T) {
+	t.Parallel()
+
+	b := memory.NewBackend(slog.Default(), &memory.Config{})
+
+	t.Cleanup(func() {
+		_ = b.(io.Closer).Close()
+	})
+
+	srv := httptest.NewServer(admin.NewHandler(slog.Default(), &testBridge{backend: b}, "secret", admin.WithDebug(0, 0)))
+	t.Cleanup(srv.Close)
+
+	// Stands in for a connection blocked in a reserve
+	blocked := make(chan struct{})
+	started := make(chan struct{})
+
+	defer close(blocked)
+
+	go pprof.Do(context.Background(), pprof.Labels(beanstalk.LabelConn, "1", beanstalk.LabelCommand, "reserve"), func(context.Context) {
+		close(started)
+		<-blocked
+	})
+
+	<-started
+
+	res, err := http.Get(srv.URL + "/debug/pprof/")
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+	require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+	res, err = http.Get(srv.URL + "/debug/pprof/goroutine?debug=1&access_token=secret")
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	res, err = http.Get(srv.URL + "/debug/goroutines?access_token=secret")
+	require.NoError(t, err)
+
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	var dump struct {
+		Total       int `json:"total"`
+		Connections []struct {
+			ID         uint64 `json:"id"`
+			Address    string `json:"address"`
+			Goroutines int    `json:"goroutines"`
+			Stacks     []struct {
+				Command string   `json:"command"`
+				Stack   []string `json:"stack"`
+			} `json:"stacks"`
+		} `json:"connections"`
+	}
+
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&dump))
+	require.Greater(t, dump.Total, 1)
+	require.Len(t, dump.Connections, 1)
+	require.Equal(t, "127.0.0.1:5000", dump.Connections[0].Address)
+	require.Equal(t, 1, dump.Connections[0].Goroutines)
+	require.Equal(t, "reserve", dump.Connections[0].Stacks[0].Command)
+	require.NotEmpty(t, dump.Connections[0].Stacks[0].Stack)
+
+	req, err := http.NewRequest(http.MethodPut, srv.URL+"/debug/profiling", strings.NewReader(`{"block_rate": 1}`))
+	require.NoError(t, err)
+
+	req.Header.Set("Authorization", "Bearer secret")
+
+	res, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+
+	defer res.Body.Close()
+
+	var profiling map[string]int
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&profiling))
+	require.Equal(t, 1, profiling["block_rate"])
+
+	runtime.SetBlockProfileRate(0)
+}
@@ -0,0 +1,331 @@
+"use strict";
+
+const pollInterval = 2000;
+const historySize = 120;
+const pageSize = 50;
+
+const state = {
+  token: localStorage.getItem("beanbridge-token") || "",
+  previous: null,
+  puts: [],
+  reserves: [],
+  tube: null,
+  jobState: "ready",
+  after: 0,
+  job: null,
+};
+
+const $ = (id) => document.getElementById(id);
+
+async function api(method, path, body) {
+  const opts = {method, headers: {}};
+
+  if (state.token) {
+    opts.headers["Authorization"] = "Bearer " + state.token;
+  }
+
+  if (body !== undefined) {
+    opts.headers["Content-Type"] = "application/json";
+    opts.body = JSON.stringify(body);
+  }
+
+  const res = await fetch(path, opts);
+
+  if (!res.ok) {
+    let message = res.statusText;
+
+    try {
+      message = (await res.json()).error;
+    } catch (e) {
+      // Keep the status text
+    }
+
+    throw new Error(method + " " + path + ": " + message);
+  }
+
+  if (res.status === 204) {
+    return null;
+  }
+
+  return res.json();
+}
+
+function showError(err) {
+  const el = $("error");
+
+  if (err) {
+    el.textContent = err.message;
+    el.hidden = false;
+  } else {
+    el.hidden = true;
+  }
+}
+
+function cell(row, text, className) {
+  const td = row.insertCell();
+  td.textContent = text;
+
+  if (className) {
+    td.className = className;
+  }
+
+  return td;
+}
+
+function link(td, text, onClick) {
+  const a = document.createElement("a");
+  a.textContent = text;
+  a.addEventListener("click", onClick);
+  td.appendChild(a);
+}
+
+function record(history, value) {
+  history.push(value);
+
+  if (history.length > historySize) {
+    history.shift();
+  }
+}
+
+function drawGraph(canvas, history) {
+  const ctx = canvas.getContext("2d");
+  const max = Math.max(1, ...history);
+
+  ctx.clearRect(0, 0, canvas.width, canvas.height);
+  ctx.strokeStyle = getComputedStyle(document.body).getPropertyValue("--accent");
+  ctx.lineWidth = 2;
+  ctx.beginPath();
+
+  history.forEach((v, i) => {
+    const x = canvas.width - (history.length - 1 - i) * (canvas.width / (historySize - 1));
+    const y = canvas.height - 4 - (v / max) * (canvas.height - 8);
+
+    if (i === 0) {
+      ctx.moveTo(x, y);
+    } else {
+      ctx.lineTo(x, y);
+    }
+  });
+
+  ctx.stroke();
+}
+
+function updateThroughput(tubes) {
+  const now = Date.now();
+  const totals = {puts: 0, reserves: 0};
+
+  for (const t of tubes) {
+    totals.puts += t.total_puts;
+    totals.reserves += t.total_reserves;
+  }
+
+  if (state.previous) {
+    const seconds = (now - state.previous.time) / 1000;
+
+    record(state.puts, Math.max(0, totals.puts - state.previous.puts) / seconds);
+    record(state.reserves, Math.max(0, totals.reserves - state.previous.reserves) / seconds);
+
+    $("puts-rate").textContent = state.puts[state.puts.length - 1].toFixed(1);
+    $("reserves-rate").textContent = state.reserves[state.reserves.length - 1].toFixed(1);
+  }
+
+  state.previous = {time: now, ...totals};
+
+  drawGraph($("puts-graph"), state.puts);
+  drawGraph($("reserves-graph"), state.reserves);
+}
+
+function renderTubes(tubes) {
+  const body = $("tubes").tBodies[0];
+  body.replaceChildren();
+
+  for (const t of tubes) {
+    const row = body.insertRow();
+
+    link(cell(row, ""), t.name, () => openTube(t.name));
+
+    for (const key of ["ready", "delayed", "reserved", "buried", "waiting", "total_puts", "total_reserves", "total_timeouts", "total_deletes"]) {
+      cell(row, t[key], "num");
+    }
+
+    cell(row, t.paused ? "paused" : "");
+  }
+}
+
+function renderClients(clients) {
+  const body = $("clients").tBodies[0];
+  body.replaceChildren();
+
+  for (const c of clients) {
+    const row = body.insertRow();
+
+    cell(row, c.id, "num");
+    cell(row, c.address);
+    cell(row, c.principal || "");
+    cell(row, c.using);
+    cell(row, c.watching.join(", "));
+    cell(row, c.reserved.join(", "));
+    cell(row, c.command ? c.command + " (" + since(c.command_started) + ")" : "");
+    cell(row, formatBytes(c.bytes_in), "num");
+    cell(row, formatBytes(c.bytes_out), "num");
+    cell(row, new Date(c.connected_at).toLocaleString());
+
+    const button = document.createElement("button");
+    button.textContent = "Disconnect";
+    button.className = "danger";
+    button.addEventListener("click", () => disconnect(c));
+    row.insertCell().appendChild(button);
+  }
+}
+
+function since(time) {
+  return Math.round((Date.now() - new Date(time)) / 1000) + "s";
+}
+
+function formatBytes(n) {
+  const units = ["B", "KiB", "MiB", "GiB"];
+  let i = 0;
+
+  while (n >= 1024 && i < units.length - 1) {
+    n /= 1024;
+    i++;
+  }
+
+  return (i === 0 ? n : n.toFixed(1)) + " " + units[i];
+}
+
+async function disconnect(client) {
+  if (!confirm("Disconnect client " + client.id + " (" + client.address + ")?")) {
+    return;
+  }
+
+  try {
+    await api("DELETE", "/api/clients/" + client.id);
+    await refresh();
+  } catch (err) {
+    showError(err);
+  }
+}
+
+async function refresh() {
+  try {
+    const [tubes, clients, drain] = await Promise.all([
+      api("GET", "/api/tubes"),
+      api("GET", "/api/clients"),
+      api("GET", "/api/drain"),
+    ]);
+
+    updateThroughput(tubes);
+    renderTubes(tubes);
+    renderClients(clients);
+
+    $("drain").hidden = !drain.draining;
+
+    showError(null);
+  } catch (err) {
+    showError(err);
+  }
+}
+
+async function loadJobs(append) {
+  const query = new URLSearchParams({state: state.jobState, after: state.after, limit: pageSize});
+  const page = await api("GET", "/api/tubes/" + encodeURIComponent(state.tube) + "/jobs?" + query);
+  const body = $("jobs").tBodies[0];
+
+  if (!append) {
+    body.replaceChildren();
+  }
+
+  for (const j of page.jobs) {
+    const row = body.insertRow();
+
+    link(cell(row, ""), j.id, () => openJob(j.id).catch(showError));
+    cell(row, j.priority, "num");
+    cell(row, j.ttr, "num");
+    cell(row, j.size, "num");
+  }
+
+  state.after = page.next || 0;
+  $("jobs-more").hidden = !page.next;
+}
+
+function openTube(name) {
+  state.tube = name;
+  state.after = 0;
+
+  $("jobs-tube").textContent = name;
+  $("jobs").hidden = false;
+  $("job").hidden = true;
+
+  for (const b of $("jobs-states").querySelectorAll("button")) {
+    b.classList.toggle("active", b.dataset.state === state.jobState);
+  }
+
+  loadJobs(false).catch(showError);
+}
+
+async function openJob(id) {
+  const job = await api("GET", "/api/jobs/" + id);
+
+  state.job = job;
+
+  $("job-id").textContent = job.id;
+  $("job").hidden = false;
+
+  const details = $("job-details");
+  details.replaceChildren();
+
+  for (const [label, value] of [["Tube", job.tube], ["State", job.state], ["Priority", job.priority], ["TTR", job.ttr], ["Size", job.size]]) {
+    const dt = document.createElement("dt");
+    const dd = document.createElement("dd");
+
+    dt.textContent = label;
+    dd.textContent = value;
+
+    details.append(dt, dd);
+  }
+
+  $("job-body").textContent = job.body !== undefined ? job.body : "(base64) " + job.body_base64;
+}
+
+async function jobAction(method, path) {
+  try {
+    await api(method, path);
+
+    $("job").hidden = true;
+
+    await refresh();
+    openTube(state.tube);
+  } catch (err) {
+    showError(err);
+  }
+}
+
+$("token").value = state.token;
+
+$("token-form").addEventListener("submit", (e) => {
+  e.preventDefault();
+
+  state.token = $("token").value;
+  localStorage.setItem("beanbridge-token", state.token);
+
+  refresh();
+});
+
+$("jobs-states").addEventListener("click", (e) => {
+  if (e.target.dataset.state) {
+    state.jobState = e.target.dataset.state;
+    openTube(state.tube);
+  }
+});
+
+$("jobs-more").addEventListener("click", () => loadJobs(true).catch(showError));
+$("job-kick").addEventListener("click", () => jobAction("POST", "/api/jobs/" + state.job.id + "/kick"));
+$("job-delete").addEventListener("click", () => {
+  if (confirm("Delete job " + state.job.id + "?")) {
+    jobAction("DELETE", "/api/jobs/" + state.job.id);
+  }
+});
+
+refresh();
+setInterval(refresh, pollInterval);
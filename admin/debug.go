@@ -0,0 +1,238 @@
+package admin
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	runtimepprof "runtime/pprof"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/csnewman/beanbridge/beanstalk"
+)
+
+type Option func(h *Handler)
+
+// WithDebug serves pprof under /debug/pprof/, a goroutine dump grouped by connection under
+// /debug/goroutines and mutex and block profiling toggles under /debug/profiling. Profiling starts
+// with the given mutex profile fraction and block profile rate, zero leaves it disabled.
+func WithDebug(mutexFraction int, blockRate int) Option {
+	return func(h *Handler) {
+		h.debug = true
+
+		runtime.SetMutexProfileFraction(mutexFraction)
+		runtime.SetBlockProfileRate(blockRate)
+		h.blockRate.Store(int64(blockRate))
+	}
+}
+
+func (h *Handler) registerDebug() {
+	h.mux.HandleFunc("GET /debug/pprof/", pprof.Index)
+	h.mux.HandleFunc("GET /debug/pprof/cmdline", pprof.Cmdline)
+	h.mux.HandleFunc("GET /debug/pprof/profile", pprof.Profile)
+	h.mux.HandleFunc("GET /debug/pprof/symbol", pprof.Symbol)
+	h.mux.HandleFunc("POST /debug/pprof/symbol", pprof.Symbol)
+	h.mux.HandleFunc("GET /debug/pprof/trace", pprof.Trace)
+	h.mux.HandleFunc("GET /debug/profiling", h.getProfiling)
+	h.mux.HandleFunc("PUT /debug/profiling", h.setProfiling)
+	h.mux.HandleFunc("GET /debug/goroutines", h.dumpGoroutines)
+}
+
+type profilingRequest struct {
+	MutexFraction *int `json:"mutex_fraction"`
+	BlockRate     *int `json:"block_rate"`
+}
+
+type profilingResponse struct {
+	MutexFraction int   `json:"mutex_fraction"`
+	BlockRate     int64 `json:"block_rate"`
+}
+
+func (h *Handler) getProfiling(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, profilingResponse{
+		MutexFraction: runtime.SetMutexProfileFraction(-1),
+		BlockRate:     h.blockRate.Load(),
+	})
+}
+
+func (h *Handler) setProfiling(w http.ResponseWriter, r *http.Request) {
+	var req profilingRequest
+
+	if err := readJSON(r, &req); err != nil {
+		h.writeError(w, err)
+
+		return
+	}
+
+	if (req.MutexFraction != nil && *req.MutexFraction < 0) || (req.BlockRate != nil && *req.BlockRate < 0) {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "rates must not be negative"})
+
+		return
+	}
+
+	if req.MutexFraction != nil {
+		runtime.SetMutexProfileFraction(*req.MutexFraction)
+	}
+
+	if req.BlockRate != nil {
+		runtime.SetBlockProfileRate(*req.BlockRate)
+		h.blockRate.Store(int64(*req.BlockRate))
+	}
+
+	h.logger.Info(
+		"Updated profiling via admin api",
+		"mutex_fraction", runtime.SetMutexProfileFraction(-1),
+		"block_rate", h.blockRate.Load(),
+		"remote", r.RemoteAddr,
+	)
+
+	h.getProfiling(w, r)
+}
+
+type goroutinesResponse struct {
+	Total       int                  `json:"total"`
+	Connections []connectionRoutines `json:"connections"`
+	Other       []goroutineStack     `json:"other"`
+}
+
+type connectionRoutines struct {
+	ID         uint64           `json:"id"`
+	Address    string           `json:"address,omitempty"`
+	Goroutines int              `json:"goroutines"`
+	Stacks     []goroutineStack `json:"stacks"`
+}
+
+type goroutineStack struct {
+	Count   int      `json:"count"`
+	Command string   `json:"command,omitempty"`
+	Stack   []string `json:"stack"`
+}
+
+// dumpGoroutines groups goroutines by the connection they serve and the command in progress, using
+// the profiler labels set by beanstalk.WithProfilerLabels.
+func (h *Handler) dumpGoroutines(w http.ResponseWriter, _ *http.Request) {
+	var buf bytes.Buffer
+
+	if err := runtimepprof.Lookup("goroutine").WriteTo(&buf, 1); err != nil {
+		h.writeError(w, err)
+
+		return
+	}
+
+	addresses := make(map[uint64]string)
+
+	for _, c := range h.bridge.Clients() {
+		addresses[c.ID] = c.Address
+	}
+
+	res := goroutinesResponse{
+		Connections: []connectionRoutines{},
+		Other:       []goroutineStack{},
+	}
+
+	conns := make(map[uint64]*connectionRoutines)
+
+	for _, rec := range parseGoroutines(&buf) {
+		res.Total += rec.Count
+
+		id, err := strconv.ParseUint(rec.labels[beanstalk.LabelConn], 10, 64)
+		if err != nil {
+			res.Other = append(res.Other, rec.goroutineStack)
+
+			continue
+		}
+
+		c, ok := conns[id]
+		if !ok {
+			c = &connectionRoutines{
+				ID:      id,
+				Address: addresses[id],
+			}
+			conns[id] = c
+		}
+
+		c.Goroutines += rec.Count
+		c.Stacks = append(c.Stacks, rec.goroutineStack)
+	}
+
+	for _, c := range conns {
+		res.Connections = append(res.Connections, *c)
+	}
+
+	slices.SortFunc(res.Connections, func(a, b connectionRoutines) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	writeJSON(w, http.StatusOK, res)
+}
+
+type goroutineRecord struct {
+	goroutineStack
+
+	labels map[string]string
+}
+
+// parseGoroutines parses a goroutine profile written with debug level 1, in which identical stacks
+// with identical labels are aggregated:
+//
+//	2 @ 0x43e2ee 0x40a4c5
+//	# labels: {"beanstalk.conn":"1", "beanstalk.command":"reserve"}
+//	#	0x43e2ed	runtime.gopark+0xcd	/usr/local/go/src/runtime/proc.go:402
+func parseGoroutines(buf *bytes.Buffer) []goroutineRecord {
+	var (
+		records []goroutineRecord
+		current *goroutineRecord
+	)
+
+	scanner := bufio.NewScanner(buf)
+	scanner.Buffer(nil, 1024*1024)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		switch {
+		case line == "":
+			current = nil
+		case strings.HasPrefix(line, "# labels: "):
+			if current != nil {
+				_ = json.Unmarshal([]byte(strings.TrimPrefix(line, "# labels: ")), &current.labels)
+			}
+		case strings.HasPrefix(line, "#\t"):
+			if current == nil {
+				continue
+			}
+
+			// Fields are the pc, function and location, with tabs used to align the locations
+			fields := slices.DeleteFunc(strings.Split(line, "\t"), func(f string) bool {
+				return f == ""
+			})
+			if len(fields) >= 4 {
+				current.Stack = append(current.Stack, fields[2]+" "+fields[3])
+			}
+		default:
+			count, _, ok := strings.Cut(line, " @ ")
+			if !ok {
+				continue
+			}
+
+			n, err := strconv.Atoi(count)
+			if err != nil {
+				continue
+			}
+
+			records = append(records, goroutineRecord{goroutineStack: goroutineStack{Count: n}})
+			current = &records[len(records)-1]
+		}
+	}
+
+	for i := range records {
+		records[i].Command = records[i].labels[beanstalk.LabelCommand]
+	}
+
+	return records
+}
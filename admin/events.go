@@ -0,0 +1,83 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/csnewman/beanbridge/events"
+)
+
+const (
+	eventBufferSize   = 256
+	keepAliveInterval = 30 * time.Second
+)
+
+// streamEvents streams job state transitions as server-sent events. The tube and type parameters
+// are comma separated lists used to filter events.
+func (h *Handler) streamEvents(w http.ResponseWriter, r *http.Request) {
+	var filter events.Filter
+
+	query := r.URL.Query()
+
+	if tubes := query.Get("tube"); tubes != "" {
+		filter.Tubes = strings.Split(tubes, ",")
+	}
+
+	if types := query.Get("type"); types != "" {
+		parsed, err := events.ParseTypes(strings.Split(types, ","))
+		if err != nil {
+			h.writeError(w, fmt.Errorf("%w: %w", errBadRequest, err))
+
+			return
+		}
+
+		filter.Types = parsed
+	}
+
+	sub := h.bridge.Events().Subscribe(filter, eventBufferSize)
+	defer sub.Close()
+
+	rc := http.NewResponseController(w)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		var err error
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			_, err = fmt.Fprint(w, ": keep-alive\n\n")
+		case e := <-sub.Events():
+			data, marshalErr := json.Marshal(e)
+			if marshalErr != nil {
+				h.logger.Error("Failed to encode event", "err", marshalErr)
+
+				continue
+			}
+
+			_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data)
+		}
+
+		if err == nil {
+			err = rc.Flush()
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
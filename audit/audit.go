@@ -0,0 +1,163 @@
+// Package audit records job lifecycle events, such as puts and deletes, to one or more sinks.
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/csnewman/beanbridge/acl"
+)
+
+type Action string
+
+const (
+	ActionPut     Action = "put"
+	ActionReserve Action = "reserve"
+	ActionDelete  Action = "delete"
+	ActionBury    Action = "bury"
+	ActionKick    Action = "kick"
+	// ActionTimeout records a reservation expiring, returning the job to the ready queue.
+	ActionTimeout Action = "timeout"
+	// ActionDenied records a command rejected by the acl, with the rejected action in Denied.
+	ActionDenied Action = "denied"
+)
+
+// Actions lists all actions that can be audited.
+var Actions = []Action{
+	ActionPut,
+	ActionReserve,
+	ActionDelete,
+	ActionBury,
+	ActionKick,
+	ActionTimeout,
+	ActionDenied,
+}
+
+// BodyMode controls how job bodies are recorded.
+type BodyMode string
+
+const (
+	BodyNone BodyMode = "none"
+	BodyHash BodyMode = "hash"
+	BodyFull BodyMode = "full"
+)
+
+// Event describes an operation on a job. The client fields are empty for events not caused by a
+// client, such as timeouts of reservations held by clients that have since disconnected. Kicks of
+// multiple jobs are recorded as a single event with a Count and no JobID, and commands denied by the
+// acl are recorded with the rejected action in Denied.
+type Event struct {
+	Time       time.Time  `json:"time"`
+	Action     Action     `json:"action"`
+	Denied     acl.Action `json:"denied,omitempty"`
+	JobID      uint64     `json:"job_id,omitempty"`
+	Count      uint64     `json:"count,omitempty"`
+	Tube       string     `json:"tube"`
+	ClientID   uint64     `json:"client_id,omitempty"`
+	Remote     string     `json:"remote,omitempty"`
+	Principal  string     `json:"principal,omitempty"`
+	BodySize   int        `json:"body_size,omitempty"`
+	BodySHA256 string     `json:"body_sha256,omitempty"`
+	Body       []byte     `json:"body,omitempty"`
+}
+
+// Sink writes audit events. Sinks must be safe for concurrent use.
+type Sink interface {
+	Write(e *Event) error
+
+	Close() error
+}
+
+// Route sends the events matching Tubes and Actions to Sink. Empty conditions match all events.
+type Route struct {
+	Sink    Sink
+	Tubes   []string
+	Actions []Action
+	Body    BodyMode
+}
+
+func (r *Route) matches(e *Event) bool {
+	if len(r.Actions) > 0 && !slices.Contains(r.Actions, e.Action) {
+		return false
+	}
+
+	if len(r.Tubes) == 0 {
+		return true
+	}
+
+	for _, t := range r.Tubes {
+		if acl.Match(t, e.Tube) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Logger fans events out to the matching routes.
+type Logger struct {
+	logger *slog.Logger
+	routes []Route
+}
+
+func NewLogger(logger *slog.Logger, routes ...Route) *Logger {
+	return &Logger{
+		logger: logger,
+		routes: routes,
+	}
+}
+
+// Record writes e to each matching sink, along with the job body if the route records bodies.
+// Failures are logged rather than returned, so that auditing never fails a command.
+func (l *Logger) Record(e Event, body []byte) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	if body != nil {
+		e.BodySize = len(body)
+	}
+
+	var hash string
+
+	for i := range l.routes {
+		r := &l.routes[i]
+
+		if !r.matches(&e) {
+			continue
+		}
+
+		routed := e
+
+		switch r.Body {
+		case BodyHash:
+			if hash == "" && body != nil {
+				sum := sha256.Sum256(body)
+				hash = hex.EncodeToString(sum[:])
+			}
+
+			routed.BodySHA256 = hash
+		case BodyFull:
+			routed.Body = body
+		}
+
+		if err := r.Sink.Write(&routed); err != nil {
+			l.logger.Error("Failed to write audit event", "action", e.Action, "id", e.JobID, "err", err)
+		}
+	}
+}
+
+// Close closes all sinks.
+func (l *Logger) Close() error {
+	var errs []error
+
+	for _, r := range l.routes {
+		errs = append(errs, r.Sink.Close())
+	}
+
+	return errors.Join(errs...)
+}
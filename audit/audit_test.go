@@ -0,0 +1,139 @@
+package audit_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/csnewman/beanbridge/audit"
+	"github.com/stretchr/testify/require"
+)
+
+type memorySink struct {
+	mu     sync.Mutex
+	events []audit.Event
+}
+
+func (s *memorySink) Write(e *audit.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.events = append(s.events, *e)
+
+	return nil
+}
+
+func (s *memorySink) Close() error {
+	return nil
+}
+
+func TestLogger(t *testing.T) {
+	t.Parallel()
+
+	all := &memorySink{}
+	payments := &memorySink{}
+
+	l := audit.NewLogger(
+		slog.Default(),
+		audit.Route{Sink: all, Body: audit.BodyNone},
+		audit.Route{
+			Sink:    payments,
+			Tubes:   []string{"payments-*"},
+			Actions: []audit.Action{audit.ActionPut},
+			Body:    audit.BodyHash,
+		},
+	)
+
+	l.Record(audit.Event{Action: audit.ActionPut, JobID: 1, Tube: "payments-eu"}, []byte("hello"))
+	l.Record(audit.Event{Action: audit.ActionDelete, JobID: 1, Tube: "payments-eu"}, nil)
+	l.Record(audit.Event{Action: audit.ActionPut, JobID: 2, Tube: "emails"}, []byte("hello"))
+
+	require.Len(t, all.events, 3)
+	require.Empty(t, all.events[0].BodySHA256)
+	require.Nil(t, all.events[0].Body)
+	require.Equal(t, 5, all.events[0].BodySize)
+	require.False(t, all.events[0].Time.IsZero())
+
+	require.Len(t, payments.events, 1)
+	require.Equal(t, uint64(1), payments.events[0].JobID)
+	require.Equal(t, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", payments.events[0].BodySHA256)
+}
+
+func TestFileSinkRotation(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	s, err := audit.OpenFile(path, 200, 2)
+	require.NoError(t, err)
+
+	for i := range 10 {
+		require.NoError(t, s.Write(&audit.Event{Action: audit.ActionPut, JobID: uint64(i + 1), Tube: "default"}))
+	}
+
+	require.NoError(t, s.Close())
+
+	for _, p := range []string{path, path + ".1", path + ".2"} {
+		info, err := os.Stat(p)
+		require.NoError(t, err)
+		require.LessOrEqual(t, info.Size(), int64(200))
+	}
+
+	_, err = os.Stat(path + ".3")
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+
+	defer f.Close()
+
+	var last audit.Event
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &last))
+	}
+
+	require.Equal(t, uint64(10), last.JobID)
+}
+
+func TestFileSinkRotationFailure(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	// A non-empty directory in place of the backup prevents the rotation
+	require.NoError(t, os.MkdirAll(filepath.Join(path+".1", "blocked"), 0o700))
+
+	s, err := audit.OpenFile(path, 100, 1)
+	require.NoError(t, err)
+
+	defer s.Close()
+
+	require.NoError(t, s.Write(&audit.Event{Action: audit.ActionPut, JobID: 1, Tube: "default"}))
+	require.ErrorContains(t, s.Write(&audit.Event{Action: audit.ActionPut, JobID: 2, Tube: "default"}), "failed to rotate")
+	require.ErrorContains(t, s.Write(&audit.Event{Action: audit.ActionPut, JobID: 3, Tube: "default"}), "failed to rotate")
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, 3, bytes.Count(data, []byte("\n")), "Events should still be written to the current file")
+
+	require.NoError(t, os.RemoveAll(path+".1"))
+	require.NoError(t, s.Write(&audit.Event{Action: audit.ActionPut, JobID: 4, Tube: "default"}), "Rotation should be retried")
+
+	data, err = os.ReadFile(path + ".1")
+	require.NoError(t, err)
+	require.Equal(t, 3, bytes.Count(data, []byte("\n")))
+
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, 1, bytes.Count(data, []byte("\n")))
+
+	require.NoError(t, s.Close())
+	require.ErrorIs(t, s.Write(&audit.Event{Action: audit.ActionPut, JobID: 5}), os.ErrClosed)
+}
@@ -0,0 +1,152 @@
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+)
+
+// FileSink writes events as JSON lines. Once the file reaches MaxSize bytes, it is rotated to
+// path.1, with older files shifted up to path.MaxBackups and the oldest removed.
+type FileSink struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	mu sync.Mutex
+	// f is nil if the file could not be reopened after a rotation, it is then reopened on the
+	// next write.
+	f      *os.File
+	size   int64
+	closed bool
+}
+
+// OpenFile opens a file sink, appending to path if it exists. A maxSize of zero disables rotation.
+func OpenFile(path string, maxSize int64, maxBackups int) (*FileSink, error) {
+	s := &FileSink{
+		path:       path,
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+	}
+
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *FileSink) open() error {
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+
+		return fmt.Errorf("failed to stat audit log: %w", err)
+	}
+
+	s.f = f
+	s.size = info.Size()
+
+	return nil
+}
+
+// Write appends the event to the file. If the file cannot be rotated, the event is still written
+// to the current file and the error returned, with rotation retried on the next write.
+func (s *FileSink) Write(e *Event) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return os.ErrClosed
+	}
+
+	if s.f == nil {
+		if err := s.open(); err != nil {
+			return err
+		}
+	}
+
+	var rotateErr error
+
+	if s.maxSize > 0 && s.size > 0 && s.size+int64(len(line)) > s.maxSize {
+		rotateErr = s.rotate()
+
+		if s.f == nil {
+			return rotateErr
+		}
+	}
+
+	n, err := s.f.Write(line)
+	s.size += int64(n)
+
+	return errors.Join(rotateErr, err)
+}
+
+// rotate moves the current file aside and opens a new one. The current file stays open until it
+// has been moved, so that a failed rotation leaves it in use.
+func (s *FileSink) rotate() error {
+	if s.maxBackups == 0 {
+		if err := os.Remove(s.path); err != nil {
+			return fmt.Errorf("failed to remove audit log: %w", err)
+		}
+	} else {
+		for i := s.maxBackups - 1; i > 0; i-- {
+			err := os.Rename(s.backupPath(i), s.backupPath(i+1))
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return fmt.Errorf("failed to rotate audit log: %w", err)
+			}
+		}
+
+		if err := os.Rename(s.path, s.backupPath(1)); err != nil {
+			return fmt.Errorf("failed to rotate audit log: %w", err)
+		}
+	}
+
+	old := s.f
+	s.f = nil
+
+	if err := old.Close(); err != nil {
+		return errors.Join(fmt.Errorf("failed to close audit log: %w", err), s.open())
+	}
+
+	return s.open()
+}
+
+func (s *FileSink) backupPath(i int) string {
+	return fmt.Sprintf("%s.%d", s.path, i)
+}
+
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+
+	s.closed = true
+
+	if s.f == nil {
+		return nil
+	}
+
+	err := s.f.Close()
+	s.f = nil
+
+	return err
+}
@@ -0,0 +1,24 @@
+//go:build !unix
+
+package audit
+
+import (
+	"errors"
+)
+
+var errSyslogUnsupported = errors.New("syslog is not supported on this platform")
+
+// SyslogSink is not supported on this platform.
+type SyslogSink struct{}
+
+func DialSyslog(_ string, _ string, _ string) (*SyslogSink, error) {
+	return nil, errSyslogUnsupported
+}
+
+func (s *SyslogSink) Write(_ *Event) error {
+	return errSyslogUnsupported
+}
+
+func (s *SyslogSink) Close() error {
+	return nil
+}
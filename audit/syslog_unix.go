@@ -0,0 +1,40 @@
+//go:build unix
+
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/syslog"
+)
+
+// SyslogSink writes events as JSON messages to syslog.
+type SyslogSink struct {
+	w *syslog.Writer
+}
+
+// DialSyslog connects to the syslog daemon at address. If network is empty, the local daemon is
+// used.
+func DialSyslog(network string, address string, tag string) (*SyslogSink, error) {
+	w, err := syslog.Dial(network, address, syslog.LOG_INFO|syslog.LOG_DAEMON, tag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to syslog: %w", err)
+	}
+
+	return &SyslogSink{
+		w: w,
+	}, nil
+}
+
+func (s *SyslogSink) Write(e *Event) error {
+	msg, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	return s.w.Info(string(msg))
+}
+
+func (s *SyslogSink) Close() error {
+	return s.w.Close()
+}
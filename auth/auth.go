@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+)
+
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// Principal identifies an authenticated client.
+type Principal struct {
+	Name string
+}
+
+// Store verifies client credentials. Implementations must return ErrInvalidCredentials when the
+// credentials are rejected, other errors are treated as internal failures.
+type Store interface {
+	Authenticate(ctx context.Context, user string, token string) (*Principal, error)
+}
+
+// Chain tries each store in turn, until one accepts the credentials.
+type Chain []Store
+
+func (c Chain) Authenticate(ctx context.Context, user string, token string) (*Principal, error) {
+	for _, s := range c {
+		p, err := s.Authenticate(ctx, user, token)
+		if errors.Is(err, ErrInvalidCredentials) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		return p, nil
+	}
+
+	return nil, ErrInvalidCredentials
+}
+
+// StaticStore authenticates against a fixed map of user names to tokens.
+type StaticStore map[string]string
+
+func (s StaticStore) Authenticate(_ context.Context, user string, token string) (*Principal, error) {
+	expected, ok := s[user]
+	if !ok || subtle.ConstantTimeCompare([]byte(expected), []byte(token)) != 1 {
+		return nil, ErrInvalidCredentials
+	}
+
+	return &Principal{
+		Name: user,
+	}, nil
+}
@@ -0,0 +1,40 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/csnewman/beanbridge/auth"
+	"github.com/stretchr/testify/require"
+)
+
+type failingStore struct{}
+
+func (failingStore) Authenticate(_ context.Context, _ string, _ string) (*auth.Principal, error) {
+	return nil, errors.New("unavailable")
+}
+
+func TestChain(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	chain := auth.Chain{
+		auth.StaticStore{"alice": "secret"},
+		auth.StaticStore{"bob": "hunter2"},
+	}
+
+	p, err := chain.Authenticate(ctx, "bob", "hunter2")
+	require.NoError(t, err, "Later stores should be tried")
+	require.Equal(t, "bob", p.Name)
+
+	_, err = chain.Authenticate(ctx, "alice", "hunter2")
+	require.ErrorIs(t, err, auth.ErrInvalidCredentials)
+
+	_, err = chain.Authenticate(ctx, "alice", "")
+	require.ErrorIs(t, err, auth.ErrInvalidCredentials)
+
+	_, err = append(chain, failingStore{}).Authenticate(ctx, "carol", "secret")
+	require.ErrorContains(t, err, "unavailable", "Internal failures should not be treated as rejections")
+	require.NotErrorIs(t, err, auth.ErrInvalidCredentials)
+}
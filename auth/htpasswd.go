@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var ErrUnsupportedHash = errors.New("unsupported password hash")
+
+// HtpasswdStore authenticates against an htpasswd style file of "user:hash" lines, where tokens
+// are hashed with bcrypt, e.g. as produced by "htpasswd -B".
+type HtpasswdStore struct {
+	hashes map[string][]byte
+}
+
+func LoadHtpasswdStore(path string) (*HtpasswdStore, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read htpasswd file: %w", err)
+	}
+
+	s := &HtpasswdStore{
+		hashes: make(map[string][]byte),
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	lineNo := 0
+
+	for scanner.Scan() {
+		lineNo++
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		user, hash, ok := strings.Cut(line, ":")
+		if !ok || user == "" {
+			return nil, fmt.Errorf("%s:%d: expected user:hash", path, lineNo)
+		}
+
+		if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w for %s", path, lineNo, ErrUnsupportedHash, user)
+		}
+
+		s.hashes[user] = []byte(hash)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read htpasswd file: %w", err)
+	}
+
+	return s, nil
+}
+
+func (s *HtpasswdStore) Authenticate(_ context.Context, user string, token string) (*Principal, error) {
+	hash, ok := s.hashes[user]
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+
+	if err := bcrypt.CompareHashAndPassword(hash, []byte(token)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	return &Principal{
+		Name: user,
+	}, nil
+}
@@ -0,0 +1,82 @@
+package auth_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/csnewman/beanbridge/auth"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func writeHtpasswd(t *testing.T, lines ...string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "htpasswd")
+
+	require.NoError(t, os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0o600))
+
+	return path
+}
+
+func hash(t *testing.T, password string) string {
+	t.Helper()
+
+	h, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	require.NoError(t, err, "Hashing should not error")
+
+	return string(h)
+}
+
+func TestHtpasswdStore(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	// htpasswd -B writes hashes with the $2y$ prefix
+	path := writeHtpasswd(
+		t,
+		"# workers",
+		"alice:"+hash(t, "secret"),
+		"",
+		"  bob:"+strings.Replace(hash(t, "hunter2"), "$2a$", "$2y$", 1)+"  ",
+	)
+
+	s, err := auth.LoadHtpasswdStore(path)
+	require.NoError(t, err, "Store should load")
+
+	p, err := s.Authenticate(ctx, "alice", "secret")
+	require.NoError(t, err, "Valid credentials should be accepted")
+	require.Equal(t, "alice", p.Name)
+
+	p, err = s.Authenticate(ctx, "bob", "hunter2")
+	require.NoError(t, err, "$2y$ hashes should be accepted")
+	require.Equal(t, "bob", p.Name)
+
+	_, err = s.Authenticate(ctx, "alice", "hunter2")
+	require.ErrorIs(t, err, auth.ErrInvalidCredentials, "Wrong passwords should be rejected")
+
+	_, err = s.Authenticate(ctx, "carol", "secret")
+	require.ErrorIs(t, err, auth.ErrInvalidCredentials, "Unknown users should be rejected")
+}
+
+func TestHtpasswdStoreErrors(t *testing.T) {
+	t.Parallel()
+
+	_, err := auth.LoadHtpasswdStore(filepath.Join(t.TempDir(), "missing"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	_, err = auth.LoadHtpasswdStore(writeHtpasswd(t, "alice:"+hash(t, "secret"), "bob"))
+	require.ErrorContains(t, err, ":2: expected user:hash")
+
+	_, err = auth.LoadHtpasswdStore(writeHtpasswd(t, ":"+hash(t, "secret")))
+	require.ErrorContains(t, err, ":1: expected user:hash")
+
+	// Apache MD5 hashes are not supported
+	_, err = auth.LoadHtpasswdStore(writeHtpasswd(t, "alice:$apr1$salt$hash"))
+	require.ErrorIs(t, err, auth.ErrUnsupportedHash)
+	require.ErrorContains(t, err, "for alice")
+}
@@ -0,0 +1,95 @@
+package backend
+
+import (
+	"context"
+
+	"github.com/csnewman/beanbridge/events"
+)
+
+type Backend interface {
+	StatsProvider
+
+	ResolveTube(name string) Tube
+
+	Put(ctx context.Context, tube Tube, pri uint64, delay uint64, ttr uint64, data []byte) (uint64, bool, error)
+
+	// Reserve blocks until a job is available from one of the tubes, the timeout (in seconds)
+	// elapses or ctx is cancelled. A negative timeout waits indefinitely.
+	Reserve(ctx context.Context, tubes []Tube, timeout int64) (*Job, error)
+
+	ReserveByID(ctx context.Context, id uint64) (*Job, error)
+
+	Delete(ctx context.Context, id uint64) error
+
+	Release(ctx context.Context, id uint64, pri uint64, delay uint64) error
+
+	Bury(ctx context.Context, id uint64, pri uint64) error
+
+	Touch(ctx context.Context, id uint64) error
+
+	// Peek returns a job without changing its state.
+	Peek(ctx context.Context, id uint64) (*Job, error)
+
+	// Kick moves up to bound jobs in the tube back into the ready queue. As with beanstalkd, only
+	// buried jobs are kicked if there are any, otherwise delayed jobs are kicked.
+	Kick(ctx context.Context, tube Tube, bound uint64) (uint64, error)
+
+	// KickJob moves a single buried or delayed job back into the ready queue.
+	KickJob(ctx context.Context, id uint64) error
+
+	// BuryJob moves a single ready or delayed job to the buried state, without reserving it.
+	BuryJob(ctx context.Context, id uint64) error
+
+	// PauseTube prevents jobs being reserved from the tube for delay seconds.
+	PauseTube(ctx context.Context, tube Tube, delay uint64) error
+
+	// ListJobs returns up to limit jobs in the tube with the given state, ordered by id, starting
+	// after the job with id after. A limit of zero or less returns no jobs.
+	ListJobs(ctx context.Context, tube string, state JobState, after uint64, limit int) ([]*Job, error)
+}
+
+type JobState string
+
+const (
+	JobReady    JobState = "ready"
+	JobDelayed  JobState = "delayed"
+	JobReserved JobState = "reserved"
+	JobBuried   JobState = "buried"
+)
+
+type Tube interface {
+	Name() string
+
+	Release()
+}
+
+type Job struct {
+	ID       uint64
+	Tube     string
+	State    JobState
+	Priority uint64
+	TTR      uint64
+	Data     []byte
+	Metadata Metadata
+
+	// Owner is the id of the client holding the reservation of a reserved job, or zero.
+	Owner uint64
+}
+
+// EventPublisher may be implemented by backends to publish job state transitions to a bus.
+type EventPublisher interface {
+	PublishEvents(bus *events.Bus)
+}
+
+// HealthChecker may be implemented by backends to report whether they can currently serve
+// requests, for example by pinging an upstream service.
+type HealthChecker interface {
+	Health(ctx context.Context) error
+}
+
+// TimeoutNotifier may be implemented by backends to report reservations expiring. The function is
+// called for each expired job, with Owner set to the client that held the reservation, before
+// blocked reserves are woken to take the job. It is called without any backend lock held.
+type TimeoutNotifier interface {
+	NotifyTimeouts(fn func(job *Job))
+}
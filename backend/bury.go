@@ -0,0 +1,19 @@
+package backend
+
+import "context"
+
+type buryKey struct{}
+
+// WithBury requests that a job is put directly into the buried state, rather than becoming ready
+// or delayed. Backends honouring the request report the job as buried from Put, so that no worker
+// can reserve the job in between.
+func WithBury(ctx context.Context) context.Context {
+	return context.WithValue(ctx, buryKey{}, true)
+}
+
+// BuryFromContext reports whether the context requests that put jobs are buried.
+func BuryFromContext(ctx context.Context) bool {
+	bury, _ := ctx.Value(buryKey{}).(bool)
+
+	return bury
+}
@@ -0,0 +1,883 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/csnewman/beanbridge/backend"
+	"github.com/csnewman/beanbridge/beanstalk"
+	"github.com/csnewman/beanbridge/events"
+)
+
+func init() {
+	backend.Register("memory", func(logger *slog.Logger, cfg *Config) (backend.Backend, error) {
+		return NewBackend(logger, cfg), nil
+	})
+}
+
+const defaultPollInterval = time.Millisecond * 500
+
+type Config struct {
+	PollInterval time.Duration `yaml:"poll-interval"`
+}
+
+type Backend struct {
+	logger *slog.Logger
+	cfg    Config
+	mu     sync.Mutex
+	tubes  map[string]*Tube
+	jobs   map[uint64]*Job
+	lastID uint64
+	wake   chan struct{}
+	stop   chan struct{}
+	done   chan struct{}
+	closed sync.Once
+
+	// events is notified of job state transitions, if set
+	events *events.Bus
+
+	// onTimeout is called for each expired reservation, if set
+	onTimeout func(job *backend.Job)
+}
+
+// NewBackend creates a memory backend. A nil cfg uses the defaults.
+func NewBackend(logger *slog.Logger, cfg *Config) backend.Backend {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+
+	b := &Backend{
+		logger: logger,
+		cfg:    *cfg,
+		tubes:  make(map[string]*Tube),
+		jobs:   make(map[uint64]*Job),
+		wake:   make(chan struct{}),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	if b.cfg.PollInterval <= 0 {
+		b.cfg.PollInterval = defaultPollInterval
+	}
+
+	go b.background()
+
+	return b
+}
+
+func (b *Backend) ResolveTube(name string) backend.Tube {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t, ok := b.tubes[name]
+	if !ok {
+		t = &Tube{
+			backend: b,
+			name:    name,
+		}
+
+		b.tubes[name] = t
+	}
+
+	return t
+}
+
+func (b *Backend) Close() error {
+	b.closed.Do(func() {
+		close(b.stop)
+	})
+
+	<-b.done
+
+	return nil
+}
+
+func (b *Backend) background() {
+	defer close(b.done)
+
+	ticker := time.NewTicker(b.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.stop:
+			return
+		case <-ticker.C:
+			b.process()
+		}
+	}
+}
+
+func (b *Backend) PublishEvents(bus *events.Bus) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.events = bus
+}
+
+func (b *Backend) publishLocked(typ events.Type, j *Job) {
+	if b.events == nil {
+		return
+	}
+
+	b.events.Publish(events.Event{
+		Type:     typ,
+		JobID:    j.ID,
+		Tube:     j.Tube.name,
+		Priority: j.Priority,
+	})
+}
+
+func (b *Backend) NotifyTimeouts(fn func(job *backend.Job)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.onTimeout = fn
+}
+
+func (b *Backend) process() {
+	b.mu.Lock()
+
+	onTimeout := b.onTimeout
+	timedOut, did := b.processLocked(time.Now())
+
+	if len(timedOut) == 0 || onTimeout == nil {
+		if did > 0 {
+			b.notifyLocked()
+		}
+
+		b.mu.Unlock()
+
+		return
+	}
+
+	b.mu.Unlock()
+
+	// The hook may block, so runs without the lock held, but before blocked reserves are woken so
+	// that the timeout is reported before a waiting client reserves the job again
+	for _, j := range timedOut {
+		onTimeout(j)
+	}
+
+	b.mu.Lock()
+	b.notifyLocked()
+	b.mu.Unlock()
+}
+
+// processLocked readies delayed jobs and expired reservations and unpauses tubes, returning the
+// jobs whose reservation expired, as they were before expiring, and the number of changes made.
+func (b *Backend) processLocked(now time.Time) ([]*backend.Job, int) {
+	var timedOut []*backend.Job
+
+	did := 0
+
+	for _, tube := range b.tubes {
+		tubeDid := 0
+
+		if !tube.pausedUntil.IsZero() && !tube.pausedUntil.After(now) {
+			tube.pausedUntil = time.Time{}
+
+			tubeDid++
+		}
+
+		for len(tube.delayed) > 0 {
+			dl := len(tube.delayed)
+
+			j := tube.delayed[dl-1]
+
+			if j.ReleaseTime.After(now) {
+				break
+			}
+
+			tube.delayed[dl-1] = nil
+			tube.delayed = tube.delayed[:dl-1]
+
+			j.state = stateReady
+			tube.ready = append(tube.ready, j)
+
+			b.publishLocked(events.TypeReady, j)
+
+			tubeDid++
+		}
+
+		for len(tube.reserved) > 0 {
+			rl := len(tube.reserved)
+
+			j := tube.reserved[rl-1]
+
+			if j.ReleaseTime.After(now) {
+				break
+			}
+
+			tube.reserved[rl-1] = nil
+			tube.reserved = tube.reserved[:rl-1]
+
+			timedOut = append(timedOut, j.export())
+
+			j.state = stateReady
+			j.owner = 0
+			tube.ready = append(tube.ready, j)
+			tube.timeouts++
+
+			b.publishLocked(events.TypeTimeout, j)
+
+			tubeDid++
+		}
+
+		if tubeDid > 0 {
+			tube.sortReady()
+		}
+
+		did += tubeDid
+	}
+
+	return timedOut, did
+}
+
+// notifyLocked wakes all blocked reserves, so they can recheck for ready jobs.
+func (b *Backend) notifyLocked() {
+	close(b.wake)
+
+	b.wake = make(chan struct{})
+}
+
+type jobState int
+
+const (
+	stateReady jobState = iota
+	stateDelayed
+	stateReserved
+	stateBuried
+)
+
+func (s jobState) export() backend.JobState {
+	switch s {
+	case stateReady:
+		return backend.JobReady
+	case stateDelayed:
+		return backend.JobDelayed
+	case stateReserved:
+		return backend.JobReserved
+	case stateBuried:
+		return backend.JobBuried
+	default:
+		panic("unexpected job state")
+	}
+}
+
+type Tube struct {
+	backend     *Backend
+	name        string
+	ready       []*Job
+	delayed     []*Job
+	reserved    []*Job
+	buried      []*Job
+	pausedUntil time.Time
+	waiting     uint64
+	puts        uint64
+	reserves    uint64
+	timeouts    uint64
+	deletes     uint64
+}
+
+func (t *Tube) Name() string {
+	return t.name
+}
+
+func (t *Tube) Release() {
+}
+
+func (t *Tube) String() string {
+	return t.name
+}
+
+func (t *Tube) sortDelayed() {
+	slices.SortFunc(t.delayed, func(a, b *Job) int {
+		if a.ReleaseTime.Before(b.ReleaseTime) {
+			return 1
+		} else if b.ReleaseTime.Before(a.ReleaseTime) {
+			return -1
+		}
+
+		return 0
+	})
+}
+
+func (t *Tube) sortReserved() {
+	slices.SortFunc(t.reserved, func(a, b *Job) int {
+		if a.ReleaseTime.Before(b.ReleaseTime) {
+			return 1
+		} else if b.ReleaseTime.Before(a.ReleaseTime) {
+			return -1
+		}
+
+		return 0
+	})
+}
+
+func (t *Tube) sortReady() {
+	// Most urgent priority latest in queue, oldest first within a priority
+	slices.SortFunc(t.ready, func(a, b *Job) int {
+		if a.Priority != b.Priority {
+			return cmp.Compare(b.Priority, a.Priority)
+		}
+
+		return cmp.Compare(b.ID, a.ID)
+	})
+}
+
+func (t *Tube) paused(now time.Time) bool {
+	return t.pausedUntil.After(now)
+}
+
+func (t *Tube) list(state jobState) *[]*Job {
+	switch state {
+	case stateReady:
+		return &t.ready
+	case stateDelayed:
+		return &t.delayed
+	case stateReserved:
+		return &t.reserved
+	case stateBuried:
+		return &t.buried
+	default:
+		panic("unexpected job state")
+	}
+}
+
+// remove detaches the job from the list matching its current state.
+func (t *Tube) remove(j *Job) {
+	l := t.list(j.state)
+
+	*l = slices.DeleteFunc(*l, func(o *Job) bool {
+		return o == j
+	})
+}
+
+type Job struct {
+	ID          uint64
+	Tube        *Tube
+	Priority    uint64
+	ReleaseTime time.Time
+	TTR         uint64
+	Data        []byte
+	Metadata    backend.Metadata
+	state       jobState
+
+	// owner is the client holding the reservation, or zero if reserved without an owner
+	owner uint64
+}
+
+func (j *Job) export() *backend.Job {
+	return &backend.Job{
+		ID:       j.ID,
+		Tube:     j.Tube.name,
+		State:    j.state.export(),
+		Priority: j.Priority,
+		TTR:      j.TTR,
+		Data:     j.Data,
+		Metadata: j.Metadata,
+		Owner:    j.owner,
+	}
+}
+
+func (b *Backend) Put(ctx context.Context, tube backend.Tube, pri uint64, delay uint64, ttr uint64, data []byte) (uint64, bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID++
+	id := b.lastID
+
+	t, ok := tube.(*Tube)
+	if !ok {
+		panic("invalid tube")
+	}
+
+	// Matches beanstalkd, which silently raises a ttr of 0 to 1 second
+	ttr = max(ttr, 1)
+
+	j := &Job{
+		ID:       id,
+		Tube:     t,
+		Data:     data,
+		Priority: pri,
+		TTR:      ttr,
+		Metadata: backend.MetadataFromContext(ctx),
+	}
+
+	b.jobs[id] = j
+	t.puts++
+
+	if backend.BuryFromContext(ctx) {
+		j.state = stateBuried
+
+		t.buried = append(t.buried, j)
+
+		b.publishLocked(events.TypePut, j)
+		b.publishLocked(events.TypeBuried, j)
+
+		return id, true, nil
+	}
+
+	b.enqueueLocked(j, delay)
+
+	b.publishLocked(events.TypePut, j)
+
+	return id, false, nil
+}
+
+// enqueueLocked places a detached job into the delayed or ready list.
+func (b *Backend) enqueueLocked(j *Job, delay uint64) {
+	t := j.Tube
+
+	if delay > 0 {
+		j.state = stateDelayed
+		j.ReleaseTime = time.Now().Add(time.Second * time.Duration(delay))
+
+		t.delayed = append(t.delayed, j)
+		t.sortDelayed()
+
+		return
+	}
+
+	j.state = stateReady
+
+	t.ready = append(t.ready, j)
+	t.sortReady()
+
+	b.notifyLocked()
+}
+
+func (b *Backend) Reserve(ctx context.Context, tubes []backend.Tube, timeout int64) (*backend.Job, error) {
+	var deadline <-chan time.Time
+
+	if timeout > 0 {
+		timer := time.NewTimer(time.Second * time.Duration(timeout))
+		defer timer.Stop()
+
+		deadline = timer.C
+	}
+
+	waiting := false
+
+	defer func() {
+		if waiting {
+			b.setWaiting(tubes, false)
+		}
+	}()
+
+	owner, _ := backend.OwnerFromContext(ctx)
+
+	for {
+		b.mu.Lock()
+		j := b.tryReserveLocked(tubes, owner)
+		wake := b.wake
+		b.mu.Unlock()
+
+		if j != nil {
+			return j, nil
+		}
+
+		if timeout == 0 {
+			return nil, beanstalk.ErrReserveTimeout
+		}
+
+		if !waiting {
+			waiting = true
+
+			b.setWaiting(tubes, true)
+		}
+
+		select {
+		case <-wake:
+		case <-deadline:
+			return nil, beanstalk.ErrReserveTimeout
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// setWaiting updates the number of clients blocked on each tube.
+func (b *Backend) setWaiting(tubes []backend.Tube, waiting bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, tube := range tubes {
+		t, ok := tube.(*Tube)
+		if !ok {
+			panic("invalid tube")
+		}
+
+		if waiting {
+			t.waiting++
+		} else {
+			t.waiting--
+		}
+	}
+}
+
+func (b *Backend) tryReserveLocked(tubes []backend.Tube, owner uint64) *backend.Job {
+	var best *Tube
+
+	now := time.Now()
+
+	for _, tube := range tubes {
+		t, ok := tube.(*Tube)
+		if !ok {
+			panic("invalid tube")
+		}
+
+		rl := len(t.ready)
+
+		if rl == 0 || t.paused(now) {
+			continue
+		}
+
+		if best == nil {
+			best = t
+
+			continue
+		}
+
+		j := t.ready[rl-1]
+		bj := best.ready[len(best.ready)-1]
+
+		if j.Priority < bj.Priority || (j.Priority == bj.Priority && j.ID < bj.ID) {
+			best = t
+		}
+	}
+
+	if best == nil {
+		return nil
+	}
+
+	rl := len(best.ready)
+
+	j := best.ready[rl-1]
+	best.ready[rl-1] = nil
+	best.ready = best.ready[:rl-1]
+
+	b.reserveLocked(j, owner)
+
+	return j.export()
+}
+
+// reserveLocked moves a detached job into the reserved list, starting its ttr.
+func (b *Backend) reserveLocked(j *Job, owner uint64) {
+	t := j.Tube
+	t.reserves++
+
+	j.state = stateReserved
+	j.owner = owner
+	j.ReleaseTime = time.Now().Add(time.Second * time.Duration(j.TTR))
+
+	t.reserved = append(t.reserved, j)
+	t.sortReserved()
+
+	b.publishLocked(events.TypeReserved, j)
+}
+
+func (b *Backend) ReserveByID(ctx context.Context, id uint64) (*backend.Job, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	j, ok := b.jobs[id]
+	if !ok || j.state == stateReserved {
+		return nil, beanstalk.ErrNotFound
+	}
+
+	owner, _ := backend.OwnerFromContext(ctx)
+
+	j.Tube.remove(j)
+
+	b.reserveLocked(j, owner)
+
+	return j.export(), nil
+}
+
+func (b *Backend) Delete(ctx context.Context, id uint64) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	j, ok := b.jobs[id]
+	if !ok {
+		return beanstalk.ErrNotFound
+	}
+
+	// As with beanstalkd, jobs reserved by another client cannot be deleted
+	if j.state == stateReserved && !ownedLocked(ctx, j) {
+		return beanstalk.ErrNotFound
+	}
+
+	j.Tube.remove(j)
+	j.Tube.deletes++
+
+	delete(b.jobs, id)
+
+	b.publishLocked(events.TypeDeleted, j)
+
+	return nil
+}
+
+func (b *Backend) Release(ctx context.Context, id uint64, pri uint64, delay uint64) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	j, ok := b.jobs[id]
+	if !ok || !ownedLocked(ctx, j) {
+		return beanstalk.ErrNotFound
+	}
+
+	j.Tube.remove(j)
+
+	j.Priority = pri
+	j.owner = 0
+
+	b.enqueueLocked(j, delay)
+
+	b.publishLocked(events.TypeReleased, j)
+
+	return nil
+}
+
+func (b *Backend) Bury(ctx context.Context, id uint64, pri uint64) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	j, ok := b.jobs[id]
+	if !ok || !ownedLocked(ctx, j) {
+		return beanstalk.ErrNotFound
+	}
+
+	j.Tube.remove(j)
+
+	j.Priority = pri
+	j.state = stateBuried
+	j.owner = 0
+
+	j.Tube.buried = append(j.Tube.buried, j)
+
+	b.publishLocked(events.TypeBuried, j)
+
+	return nil
+}
+
+func (b *Backend) Touch(ctx context.Context, id uint64) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	j, ok := b.jobs[id]
+	if !ok || !ownedLocked(ctx, j) {
+		return beanstalk.ErrNotFound
+	}
+
+	j.ReleaseTime = time.Now().Add(time.Second * time.Duration(j.TTR))
+
+	j.Tube.sortReserved()
+
+	return nil
+}
+
+// ownedLocked reports whether the job is reserved and may be modified by the owner in ctx.
+func ownedLocked(ctx context.Context, j *Job) bool {
+	if j.state != stateReserved {
+		return false
+	}
+
+	owner, ok := backend.OwnerFromContext(ctx)
+
+	return !ok || j.owner == owner
+}
+
+func (b *Backend) Peek(_ context.Context, id uint64) (*backend.Job, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	j, ok := b.jobs[id]
+	if !ok {
+		return nil, beanstalk.ErrNotFound
+	}
+
+	return j.export(), nil
+}
+
+func (b *Backend) Kick(_ context.Context, tube backend.Tube, bound uint64) (uint64, error) {
+	t, ok := tube.(*Tube)
+	if !ok {
+		panic("invalid tube")
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var kicked uint64
+
+	if len(t.buried) > 0 {
+		// Oldest buried jobs first
+		for len(t.buried) > 0 && kicked < bound {
+			j := t.buried[0]
+
+			t.buried[0] = nil
+			t.buried = t.buried[1:]
+
+			b.enqueueLocked(j, 0)
+			b.publishLocked(events.TypeKicked, j)
+
+			kicked++
+		}
+
+		return kicked, nil
+	}
+
+	// Delayed jobs are kicked in the order they would become ready
+	for len(t.delayed) > 0 && kicked < bound {
+		dl := len(t.delayed)
+
+		j := t.delayed[dl-1]
+
+		t.delayed[dl-1] = nil
+		t.delayed = t.delayed[:dl-1]
+
+		b.enqueueLocked(j, 0)
+		b.publishLocked(events.TypeKicked, j)
+
+		kicked++
+	}
+
+	return kicked, nil
+}
+
+func (b *Backend) KickJob(_ context.Context, id uint64) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	j, ok := b.jobs[id]
+	if !ok || (j.state != stateBuried && j.state != stateDelayed) {
+		return beanstalk.ErrNotFound
+	}
+
+	j.Tube.remove(j)
+
+	b.enqueueLocked(j, 0)
+
+	b.publishLocked(events.TypeKicked, j)
+
+	return nil
+}
+
+func (b *Backend) BuryJob(_ context.Context, id uint64) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	j, ok := b.jobs[id]
+	if !ok || (j.state != stateReady && j.state != stateDelayed) {
+		return beanstalk.ErrNotFound
+	}
+
+	j.Tube.remove(j)
+
+	j.state = stateBuried
+
+	j.Tube.buried = append(j.Tube.buried, j)
+
+	b.publishLocked(events.TypeBuried, j)
+
+	return nil
+}
+
+func (b *Backend) PauseTube(_ context.Context, tube backend.Tube, delay uint64) error {
+	t, ok := tube.(*Tube)
+	if !ok {
+		panic("invalid tube")
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if delay == 0 {
+		t.pausedUntil = time.Time{}
+
+		b.notifyLocked()
+
+		return nil
+	}
+
+	t.pausedUntil = time.Now().Add(time.Second * time.Duration(delay))
+
+	return nil
+}
+
+func (b *Backend) TubeStats(_ context.Context) ([]backend.TubeStats, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	stats := make([]backend.TubeStats, 0, len(b.tubes))
+
+	for _, t := range b.tubes {
+		stats = append(stats, backend.TubeStats{
+			Name:          t.name,
+			Ready:         uint64(len(t.ready)),
+			Delayed:       uint64(len(t.delayed)),
+			Reserved:      uint64(len(t.reserved)),
+			Buried:        uint64(len(t.buried)),
+			Waiting:       t.waiting,
+			Paused:        t.paused(now),
+			TotalPuts:     t.puts,
+			TotalReserves: t.reserves,
+			TotalTimeouts: t.timeouts,
+			TotalDeletes:  t.deletes,
+		})
+	}
+
+	slices.SortFunc(stats, func(a, b backend.TubeStats) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+
+	return stats, nil
+}
+
+func (b *Backend) ListJobs(_ context.Context, tube string, state backend.JobState, after uint64, limit int) ([]*backend.Job, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t, ok := b.tubes[tube]
+	if !ok {
+		return nil, nil
+	}
+
+	var matching []*Job
+
+	for _, list := range [][]*Job{t.ready, t.delayed, t.reserved, t.buried} {
+		for _, j := range list {
+			if j.ID > after && j.state.export() == state {
+				matching = append(matching, j)
+			}
+		}
+	}
+
+	slices.SortFunc(matching, func(a, b *Job) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	if len(matching) > limit {
+		matching = matching[:limit]
+	}
+
+	jobs := make([]*backend.Job, 0, len(matching))
+
+	for _, j := range matching {
+		jobs = append(jobs, j.export())
+	}
+
+	return jobs, nil
+}
@@ -0,0 +1,274 @@
+package memory_test
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/csnewman/beanbridge/backend"
+	"github.com/csnewman/beanbridge/backend/memory"
+	"github.com/csnewman/beanbridge/beanstalk"
+	"github.com/neilotoole/slogt"
+	"github.com/stretchr/testify/require"
+)
+
+func newBackend(t *testing.T) backend.Backend {
+	t.Helper()
+
+	b := memory.NewBackend(slogt.New(t), &memory.Config{PollInterval: 10 * time.Millisecond})
+
+	t.Cleanup(func() {
+		require.NoError(t, b.(io.Closer).Close(), "Close should not error")
+	})
+
+	return b
+}
+
+func TestStates(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	b := newBackend(t)
+	tube := b.ResolveTube("default")
+
+	id, buried, err := b.Put(ctx, tube, 10, 0, 60, []byte("hello"))
+	require.NoError(t, err, "Put should not error")
+	require.False(t, buried)
+
+	job, err := b.Reserve(ctx, []backend.Tube{tube}, 0)
+	require.NoError(t, err, "Reserve should not error")
+	require.Equal(t, id, job.ID)
+	require.Equal(t, backend.JobReserved, job.State)
+
+	_, err = b.Reserve(ctx, []backend.Tube{tube}, 0)
+	require.ErrorIs(t, err, beanstalk.ErrReserveTimeout, "Reserved jobs should not be reserved again")
+
+	require.NoError(t, b.Touch(ctx, id), "Touch should not error")
+	require.NoError(t, b.Bury(ctx, id, 20), "Bury should not error")
+	require.ErrorIs(t, b.Release(ctx, id, 10, 0), beanstalk.ErrNotFound, "Buried jobs cannot be released")
+
+	job, err = b.Peek(ctx, id)
+	require.NoError(t, err, "Peek should not error")
+	require.Equal(t, backend.JobBuried, job.State)
+	require.Equal(t, uint64(20), job.Priority)
+
+	require.NoError(t, b.KickJob(ctx, id), "KickJob should not error")
+	require.ErrorIs(t, b.KickJob(ctx, id), beanstalk.ErrNotFound, "Ready jobs cannot be kicked")
+
+	job, err = b.ReserveByID(ctx, id)
+	require.NoError(t, err, "ReserveByID should not error")
+	require.Equal(t, backend.JobReserved, job.State)
+
+	require.NoError(t, b.Release(ctx, id, 5, 60), "Release should not error")
+
+	job, err = b.Peek(ctx, id)
+	require.NoError(t, err, "Peek should not error")
+	require.Equal(t, backend.JobDelayed, job.State)
+
+	kicked, err := b.Kick(ctx, tube, 10)
+	require.NoError(t, err, "Kick should not error")
+	require.Equal(t, uint64(1), kicked, "Delayed job should be kicked")
+
+	require.NoError(t, b.Delete(ctx, id), "Delete should not error")
+	require.ErrorIs(t, b.Delete(ctx, id), beanstalk.ErrNotFound)
+	require.ErrorIs(t, b.Touch(ctx, id), beanstalk.ErrNotFound)
+}
+
+func TestPutBuried(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	b := newBackend(t)
+	tube := b.ResolveTube("default")
+
+	id, buried, err := b.Put(backend.WithBury(ctx), tube, 10, 5, 60, []byte("hello"))
+	require.NoError(t, err, "Put should not error")
+	require.True(t, buried, "Job should be reported as buried")
+
+	_, err = b.Reserve(ctx, []backend.Tube{tube}, 0)
+	require.ErrorIs(t, err, beanstalk.ErrReserveTimeout, "Buried jobs should not be reserved")
+
+	job, err := b.Peek(ctx, id)
+	require.NoError(t, err, "Peek should not error")
+	require.Equal(t, backend.JobBuried, job.State)
+
+	kicked, err := b.Kick(ctx, tube, 10)
+	require.NoError(t, err, "Kick should not error")
+	require.Equal(t, uint64(1), kicked)
+
+	job, err = b.Reserve(ctx, []backend.Tube{tube}, 0)
+	require.NoError(t, err, "Kicked job should be reserved")
+	require.Equal(t, id, job.ID)
+}
+
+func TestOrdering(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	b := newBackend(t)
+	low := b.ResolveTube("low")
+	high := b.ResolveTube("high")
+
+	first, _, err := b.Put(ctx, low, 10, 0, 60, nil)
+	require.NoError(t, err, "Put should not error")
+
+	second, _, err := b.Put(ctx, high, 5, 0, 60, nil)
+	require.NoError(t, err, "Put should not error")
+
+	third, _, err := b.Put(ctx, low, 5, 0, 60, nil)
+	require.NoError(t, err, "Put should not error")
+
+	for _, want := range []uint64{second, third, first} {
+		job, err := b.Reserve(ctx, []backend.Tube{low, high}, 0)
+		require.NoError(t, err, "Reserve should not error")
+		require.Equal(t, want, job.ID, "Most urgent then oldest job should be reserved first")
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	b := newBackend(t)
+	tube := b.ResolveTube("default")
+
+	timeouts := make(chan *backend.Job, 1)
+
+	b.(backend.TimeoutNotifier).NotifyTimeouts(func(job *backend.Job) {
+		// The hook runs without the backend lock held, so may call back into the backend
+		if _, err := b.Peek(ctx, job.ID); err != nil {
+			t.Errorf("Peek should not error: %v", err)
+		}
+
+		timeouts <- job
+	})
+
+	// A ttr of zero is raised to one second
+	id, _, err := b.Put(ctx, tube, 1, 0, 0, []byte("hello"))
+	require.NoError(t, err, "Put should not error")
+
+	first := backend.WithOwner(ctx, 1)
+	second := backend.WithOwner(ctx, 2)
+
+	job, err := b.Reserve(first, []backend.Tube{tube}, 0)
+	require.NoError(t, err, "Reserve should not error")
+	require.Equal(t, uint64(1), job.TTR)
+	require.Equal(t, uint64(1), job.Owner)
+
+	require.ErrorIs(t, b.Touch(second, id), beanstalk.ErrNotFound, "Other owners cannot touch the job")
+	require.ErrorIs(t, b.Delete(second, id), beanstalk.ErrNotFound, "Other owners cannot delete the job")
+
+	job, err = b.Reserve(second, []backend.Tube{tube}, 5)
+	require.NoError(t, err, "Reserve should wait for the ttr to expire")
+	require.Equal(t, id, job.ID)
+	require.Equal(t, uint64(2), job.Owner)
+
+	expired := <-timeouts
+	require.Equal(t, id, expired.ID)
+	require.Equal(t, uint64(1), expired.Owner, "Timeout should report the previous owner")
+
+	require.ErrorIs(t, b.Release(first, id, 1, 0), beanstalk.ErrNotFound, "Previous owner cannot release the job")
+	require.ErrorIs(t, b.Bury(first, id, 1), beanstalk.ErrNotFound, "Previous owner cannot bury the job")
+	require.NoError(t, b.Touch(ctx, id), "Requests without an owner should not be restricted")
+	require.NoError(t, b.Delete(second, id), "Owner should delete the job")
+
+	stats, err := b.TubeStats(ctx)
+	require.NoError(t, err, "TubeStats should not error")
+	require.Len(t, stats, 1)
+	require.Equal(t, uint64(1), stats[0].TotalTimeouts)
+	require.Equal(t, uint64(2), stats[0].TotalReserves)
+	require.Equal(t, uint64(1), stats[0].TotalDeletes)
+}
+
+func TestPauseTube(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	b := newBackend(t)
+	tube := b.ResolveTube("default")
+
+	_, _, err := b.Put(ctx, tube, 1, 0, 60, nil)
+	require.NoError(t, err, "Put should not error")
+
+	require.NoError(t, b.PauseTube(ctx, tube, 60), "PauseTube should not error")
+
+	_, err = b.Reserve(ctx, []backend.Tube{tube}, 0)
+	require.ErrorIs(t, err, beanstalk.ErrReserveTimeout, "Paused tubes should not be reserved from")
+
+	stats, err := b.TubeStats(ctx)
+	require.NoError(t, err, "TubeStats should not error")
+	require.True(t, stats[0].Paused)
+
+	reserved := make(chan error, 1)
+
+	go func() {
+		_, err := b.Reserve(ctx, []backend.Tube{tube}, 5)
+		reserved <- err
+	}()
+
+	require.Eventually(t, func() bool {
+		stats, err := b.TubeStats(ctx)
+
+		return err == nil && stats[0].Waiting == 1
+	}, 5*time.Second, 10*time.Millisecond, "Reserve should wait on the tube")
+
+	require.NoError(t, b.PauseTube(ctx, tube, 0), "Unpausing should not error")
+	require.NoError(t, <-reserved, "Waiting reserve should succeed once unpaused")
+}
+
+func TestListJobs(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	b := newBackend(t)
+	tube := b.ResolveTube("default")
+
+	var ids []uint64
+
+	for range 5 {
+		id, _, err := b.Put(ctx, tube, 1, 0, 60, nil)
+		require.NoError(t, err, "Put should not error")
+
+		ids = append(ids, id)
+	}
+
+	_, _, err := b.Put(ctx, tube, 1, 60, 60, nil)
+	require.NoError(t, err, "Put should not error")
+
+	_, err = b.ReserveByID(ctx, ids[1])
+	require.NoError(t, err, "ReserveByID should not error")
+
+	jobs, err := b.ListJobs(ctx, "default", backend.JobReady, 0, 2)
+	require.NoError(t, err, "ListJobs should not error")
+	require.Len(t, jobs, 2)
+	require.Equal(t, ids[0], jobs[0].ID)
+	require.Equal(t, ids[2], jobs[1].ID)
+
+	jobs, err = b.ListJobs(ctx, "default", backend.JobReady, jobs[1].ID, 10)
+	require.NoError(t, err, "ListJobs should not error")
+	require.Len(t, jobs, 2, "Listing should continue after the last id")
+
+	jobs, err = b.ListJobs(ctx, "default", backend.JobDelayed, 0, 10)
+	require.NoError(t, err, "ListJobs should not error")
+	require.Len(t, jobs, 1)
+
+	jobs, err = b.ListJobs(ctx, "default", backend.JobReady, 0, -1)
+	require.NoError(t, err, "Negative limits should not error")
+	require.Empty(t, jobs)
+
+	jobs, err = b.ListJobs(ctx, "missing", backend.JobReady, 0, 10)
+	require.NoError(t, err, "ListJobs should not error")
+	require.Empty(t, jobs)
+
+	stats, err := b.TubeStats(ctx)
+	require.NoError(t, err, "TubeStats should not error")
+	require.Equal(t, []backend.TubeStats{{
+		Name:          "default",
+		Ready:         4,
+		Delayed:       1,
+		Reserved:      1,
+		TotalPuts:     6,
+		TotalReserves: 1,
+	}}, stats)
+}
@@ -0,0 +1,22 @@
+package backend
+
+import "context"
+
+// Metadata holds auxiliary data stored alongside a job, such as trace context. Backends that
+// support metadata store the metadata found in the context passed to Put, and return it with the
+// job once reserved.
+type Metadata map[string]string
+
+type metadataKey struct{}
+
+// WithMetadata attaches job metadata to a context, to be stored by Put.
+func WithMetadata(ctx context.Context, md Metadata) context.Context {
+	return context.WithValue(ctx, metadataKey{}, md)
+}
+
+// MetadataFromContext returns the metadata attached to the context, if any.
+func MetadataFromContext(ctx context.Context) Metadata {
+	md, _ := ctx.Value(metadataKey{}).(Metadata)
+
+	return md
+}
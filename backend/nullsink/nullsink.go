@@ -0,0 +1,124 @@
+package nullsink
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/csnewman/beanbridge/backend"
+	"github.com/csnewman/beanbridge/beanstalk"
+)
+
+func init() {
+	backend.Register("nullsink", func(logger *slog.Logger, _ *Config) (backend.Backend, error) {
+		return NewBackend(logger), nil
+	})
+}
+
+type Config struct{}
+
+type Backend struct {
+	logger *slog.Logger
+	lastID atomic.Uint64
+}
+
+func NewBackend(logger *slog.Logger) backend.Backend {
+	return &Backend{
+		logger: logger,
+	}
+}
+
+type Tube struct {
+	Backend *Backend
+	name    string
+}
+
+func (b *Backend) ResolveTube(name string) backend.Tube {
+	return &Tube{
+		Backend: b,
+		name:    name,
+	}
+}
+
+func (t *Tube) Name() string {
+	return t.name
+}
+
+func (t *Tube) Release() {
+}
+
+func (b *Backend) Put(_ context.Context, tube backend.Tube, _ uint64, _ uint64, _ uint64, body []byte) (uint64, bool, error) {
+	id := b.lastID.Add(1)
+
+	b.logger.Debug("Dropping message", "tube", tube.Name(), "bytes", len(body))
+
+	return id, false, nil
+}
+
+func (b *Backend) Reserve(ctx context.Context, _ []backend.Tube, timeout int64) (*backend.Job, error) {
+	// Nothing is ever stored, so wait out the timeout
+	if timeout < 0 {
+		<-ctx.Done()
+
+		return nil, ctx.Err()
+	}
+
+	timer := time.NewTimer(time.Second * time.Duration(timeout))
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil, beanstalk.ErrReserveTimeout
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (b *Backend) ReserveByID(_ context.Context, _ uint64) (*backend.Job, error) {
+	return nil, beanstalk.ErrNotFound
+}
+
+func (b *Backend) Delete(_ context.Context, _ uint64) error {
+	return beanstalk.ErrNotFound
+}
+
+func (b *Backend) Release(_ context.Context, _ uint64, _ uint64, _ uint64) error {
+	return beanstalk.ErrNotFound
+}
+
+func (b *Backend) Bury(_ context.Context, _ uint64, _ uint64) error {
+	return beanstalk.ErrNotFound
+}
+
+func (b *Backend) Touch(_ context.Context, _ uint64) error {
+	return beanstalk.ErrNotFound
+}
+
+func (b *Backend) Peek(_ context.Context, _ uint64) (*backend.Job, error) {
+	return nil, beanstalk.ErrNotFound
+}
+
+func (b *Backend) Kick(_ context.Context, _ backend.Tube, _ uint64) (uint64, error) {
+	return 0, nil
+}
+
+func (b *Backend) KickJob(_ context.Context, _ uint64) error {
+	return beanstalk.ErrNotFound
+}
+
+func (b *Backend) BuryJob(_ context.Context, _ uint64) error {
+	return beanstalk.ErrNotFound
+}
+
+func (b *Backend) PauseTube(_ context.Context, _ backend.Tube, _ uint64) error {
+	return nil
+}
+
+func (b *Backend) TubeStats(_ context.Context) ([]backend.TubeStats, error) {
+	return nil, nil
+}
+
+func (b *Backend) ListJobs(_ context.Context, _ string, _ backend.JobState, _ uint64, _ int) ([]*backend.Job, error) {
+	return nil, nil
+}
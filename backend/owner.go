@@ -0,0 +1,20 @@
+package backend
+
+import "context"
+
+type ownerKey struct{}
+
+// WithOwner attaches the id of the client making a request to a context. Backends record the
+// owner of a job when it is reserved, and reject releases, burials, touches and deletes of the
+// job made on behalf of any other owner. Requests without an owner, such as those made through
+// the admin API, are not restricted.
+func WithOwner(ctx context.Context, owner uint64) context.Context {
+	return context.WithValue(ctx, ownerKey{}, owner)
+}
+
+// OwnerFromContext returns the owner attached to the context, if any.
+func OwnerFromContext(ctx context.Context) (uint64, bool) {
+	owner, ok := ctx.Value(ownerKey{}).(uint64)
+
+	return owner, ok
+}
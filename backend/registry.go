@@ -0,0 +1,114 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+)
+
+var ErrUnknownBackend = errors.New("unknown backend type")
+
+// Decoder decodes a backend specific configuration section into v.
+type Decoder func(v any) error
+
+// Factory creates a backend from its configuration section.
+type Factory func(logger *slog.Logger, decode Decoder) (Backend, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Factory)
+	// validators decode the configuration of backends registered with Register, without creating
+	// the backend
+	validators = make(map[string]func(decode Decoder) error)
+)
+
+// Register makes a backend available under the given name. The factory receives a
+// freshly decoded C for each backend created. Register panics if the name is reused.
+func Register[C any](name string, factory func(logger *slog.Logger, cfg *C) (Backend, error)) {
+	if factory == nil {
+		panic("backend: Register factory is nil")
+	}
+
+	RegisterFactory(name, func(logger *slog.Logger, decode Decoder) (Backend, error) {
+		cfg := new(C)
+
+		if err := decode(cfg); err != nil {
+			return nil, fmt.Errorf("invalid %s backend config: %w", name, err)
+		}
+
+		return factory(logger, cfg)
+	})
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	validators[name] = func(decode Decoder) error {
+		return decode(new(C))
+	}
+}
+
+// RegisterFactory is like Register, but leaves decoding of the configuration to the factory.
+func RegisterFactory(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if factory == nil {
+		panic("backend: Register factory is nil")
+	}
+
+	if _, dup := registry[name]; dup {
+		panic("backend: Register called twice for " + name)
+	}
+
+	registry[name] = factory
+}
+
+// New creates a backend using the factory registered under name.
+func New(name string, logger *slog.Logger, decode Decoder) (Backend, error) {
+	registryMu.RLock()
+	factory, ok := registry[name]
+	registryMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownBackend, name)
+	}
+
+	return factory(logger, decode)
+}
+
+// ValidateConfig checks that the configuration section of the named backend decodes. Backends
+// registered with RegisterFactory decode their own configuration, so are not checked.
+func ValidateConfig(name string, decode Decoder) error {
+	registryMu.RLock()
+	validate, ok := validators[name]
+	_, registered := registry[name]
+	registryMu.RUnlock()
+
+	if !registered {
+		return fmt.Errorf("%w: %s", ErrUnknownBackend, name)
+	}
+
+	if !ok {
+		return nil
+	}
+
+	return validate(decode)
+}
+
+// Names returns the sorted names of all registered backends.
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+
+	for name := range registry {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	return names
+}
@@ -0,0 +1,75 @@
+package backend_test
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/csnewman/beanbridge/backend"
+	"github.com/csnewman/beanbridge/backend/memory"
+	"github.com/stretchr/testify/require"
+)
+
+type testConfig struct {
+	Name string
+}
+
+// created records the config passed to the registry-test factory
+var created *testConfig
+
+func init() {
+	backend.Register("registry-test", func(logger *slog.Logger, cfg *testConfig) (backend.Backend, error) {
+		created = cfg
+
+		return memory.NewBackend(logger, nil), nil
+	})
+}
+
+func TestRegistry(t *testing.T) {
+	t.Parallel()
+
+	require.Contains(t, backend.Names(), "registry-test")
+	require.Contains(t, backend.Names(), "memory")
+
+	b, err := backend.New("registry-test", slog.Default(), func(v any) error {
+		v.(*testConfig).Name = "decoded"
+
+		return nil
+	})
+	require.NoError(t, err, "Backend should be created")
+
+	defer b.(io.Closer).Close()
+
+	require.Equal(t, "decoded", created.Name, "Factory should receive the decoded config")
+
+	_, err = backend.New("registry-test", slog.Default(), func(any) error {
+		return errors.New("bad field")
+	})
+	require.ErrorContains(t, err, "invalid registry-test backend config: bad field")
+
+	require.NoError(t, backend.ValidateConfig("registry-test", func(any) error {
+		return nil
+	}))
+	require.ErrorContains(t, backend.ValidateConfig("registry-test", func(any) error {
+		return errors.New("bad field")
+	}), "bad field")
+	require.ErrorIs(t, backend.ValidateConfig("missing", nil), backend.ErrUnknownBackend)
+
+	_, err = backend.New("missing", slog.Default(), nil)
+	require.ErrorIs(t, err, backend.ErrUnknownBackend)
+
+	require.Panics(t, func() {
+		backend.Register("registry-test", func(*slog.Logger, *testConfig) (backend.Backend, error) {
+			return b, nil
+		})
+	}, "Duplicate names should panic")
+
+	require.Panics(t, func() {
+		backend.Register[testConfig]("registry-nil", nil)
+	}, "Nil factories should panic")
+
+	require.Panics(t, func() {
+		backend.RegisterFactory("registry-nil", nil)
+	}, "Nil factories should panic")
+}
@@ -0,0 +1,26 @@
+package backend
+
+import "context"
+
+// StatsProvider reports statistics for the tubes of a backend.
+type StatsProvider interface {
+	TubeStats(ctx context.Context) ([]TubeStats, error)
+}
+
+// TubeStats holds the current job counts of a tube, along with counters since the backend started.
+type TubeStats struct {
+	Name     string
+	Ready    uint64
+	Delayed  uint64
+	Reserved uint64
+	Buried   uint64
+	// Waiting is the number of clients blocked in a reserve watching the tube.
+	Waiting uint64
+	Paused  bool
+
+	TotalPuts     uint64
+	TotalReserves uint64
+	// TotalTimeouts counts reserved jobs returned to the tube after their ttr expired.
+	TotalTimeouts uint64
+	TotalDeletes  uint64
+}
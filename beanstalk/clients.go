@@ -0,0 +1,133 @@
+package beanstalk
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// ClientInfo describes a connected client, as reported by the list-clients command.
+type ClientInfo struct {
+	ID        uint64
+	Address   string
+	Principal string
+	Using     string
+	Watching  []string
+	Reserved  []uint64
+	// Command is the command currently being processed, such as a blocked reserve, or empty if the
+	// client is idle.
+	Command        string
+	CommandStarted time.Time
+	BytesIn        uint64
+	BytesOut       uint64
+	ConnectedAt    time.Time
+}
+
+// ClientManager may be implemented by a Handler to support the "list-clients" and
+// "disconnect-client <id>" extension commands. DisconnectClient should return ErrNotFound for
+// unknown clients.
+type ClientManager interface {
+	ListClients(ctx context.Context) ([]ClientInfo, error)
+
+	DisconnectClient(ctx context.Context, id uint64) error
+}
+
+type activeCommand struct {
+	name    string
+	started time.Time
+}
+
+// ID returns the identifier of the connection, unique for the lifetime of the server.
+func (c *Conn) ID() uint64 {
+	return c.id
+}
+
+// Info returns the protocol level state of the connection. Handlers are expected to fill in their
+// own state, such as the principal and tubes.
+func (c *Conn) Info() ClientInfo {
+	info := ClientInfo{
+		ID:          c.id,
+		Address:     c.rwc.RemoteAddr().String(),
+		BytesIn:     c.bytesIn.Load(),
+		BytesOut:    c.w.written.Load(),
+		ConnectedAt: c.since,
+	}
+
+	if cmd := c.command.Load(); cmd != nil {
+		info.Command = cmd.name
+		info.CommandStarted = cmd.started
+	}
+
+	return info
+}
+
+// Disconnect closes the connection, interrupting any command in progress. It may be called from
+// any goroutine.
+func (c *Conn) Disconnect() {
+	if c.disconnected.Swap(true) {
+		return
+	}
+
+	c.cancel()
+
+	_ = c.rwc.Close()
+}
+
+// clientsYAML formats clients as the YAML list returned by the list-clients command.
+func clientsYAML(clients []ClientInfo) string {
+	var b strings.Builder
+
+	b.WriteString("---\n")
+
+	now := time.Now()
+
+	for _, c := range clients {
+		fmt.Fprintf(&b, "- id: %d\n", c.ID)
+		fmt.Fprintf(&b, "  address: %q\n", c.Address)
+
+		if c.Principal != "" {
+			fmt.Fprintf(&b, "  principal: %q\n", c.Principal)
+		}
+
+		fmt.Fprintf(&b, "  using: %s\n", c.Using)
+		fmt.Fprintf(&b, "  watching: [%s]\n", strings.Join(c.Watching, ", "))
+		fmt.Fprintf(&b, "  reserved: [%s]\n", joinUints(c.Reserved))
+
+		if c.Command != "" {
+			fmt.Fprintf(&b, "  command: %s\n", c.Command)
+			fmt.Fprintf(&b, "  command-time: %d\n", int64(now.Sub(c.CommandStarted).Seconds()))
+		}
+
+		fmt.Fprintf(&b, "  bytes-in: %d\n", c.BytesIn)
+		fmt.Fprintf(&b, "  bytes-out: %d\n", c.BytesOut)
+		fmt.Fprintf(&b, "  age: %d\n", int64(now.Sub(c.ConnectedAt).Seconds()))
+	}
+
+	return b.String()
+}
+
+func joinUints(vs []uint64) string {
+	parts := make([]string, len(vs))
+
+	for i, v := range vs {
+		parts[i] = fmt.Sprint(v)
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// countingReader counts the bytes read from the underlying reader.
+type countingReader struct {
+	r io.Reader
+	n *atomic.Uint64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n.Add(uint64(n))
+
+	return n, err
+}
@@ -0,0 +1,807 @@
+package beanstalk
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	ErrBadFormat      = errors.New("bad format")
+	ErrReserveTimeout = errors.New("reserve timeout")
+	ErrDeadlineSoon   = errors.New("deadline soon")
+	ErrNotFound       = errors.New("not found")
+	ErrNotIgnored     = errors.New("not ignored")
+	ErrDraining       = errors.New("draining")
+	ErrJobTooBig      = errors.New("job too big")
+	ErrOutOfMemory    = errors.New("out of memory")
+	ErrAuthFailed     = errors.New("auth failed")
+	ErrPermission     = errors.New("permission denied")
+	ErrThrottled      = errors.New("throttled")
+
+	errQuit         = errors.New("quit")
+	errWriteTimeout = errors.New("write timeout")
+	errDisconnected = errors.New("disconnected")
+	errStreamEnded  = errors.New("stream ended")
+)
+
+type Factory func(conn *Conn) Handler
+
+type connState int32
+
+const (
+	connActive connState = iota
+	connIdle
+)
+
+type Conn struct {
+	logger       *slog.Logger
+	server       *Server
+	id           uint64
+	since        time.Time
+	ctx          context.Context
+	cancel       context.CancelFunc
+	rwc          net.Conn
+	reader       *bufio.Reader
+	bytesIn      atomic.Uint64
+	w            *deadlineWriter
+	handler      Handler
+	state        atomic.Int32
+	command      atomic.Pointer[activeCommand]
+	disconnected atomic.Bool
+	// logged is set if the current command was logged, so that its body may be logged too
+	logged bool
+}
+
+// Handler processes the commands of a single connection. Commands are issued sequentially. The
+// context passed to each command is cancelled when the server shuts down. If the handler
+// implements io.Closer, it is closed once the connection ends.
+type Handler interface {
+	Put(ctx context.Context, pri uint64, delay uint64, ttr uint64, data []byte) (uint64, bool, error)
+
+	Use(ctx context.Context, tube string) (string, error)
+
+	Reserve(ctx context.Context, timeout int64) (uint64, []byte, error)
+
+	ReserveByID(ctx context.Context, id uint64) (uint64, []byte, error)
+
+	Delete(ctx context.Context, id uint64) error
+
+	Release(ctx context.Context, id uint64, pri uint64, delay uint64) error
+
+	Bury(ctx context.Context, id uint64, pri uint64) error
+
+	Touch(ctx context.Context, id uint64) error
+
+	Watch(ctx context.Context, tube string) (int, error)
+
+	Ignore(ctx context.Context, tube string) (int, error)
+
+	Kick(ctx context.Context, bound uint64) (uint64, error)
+
+	KickJob(ctx context.Context, id uint64) error
+
+	PauseTube(ctx context.Context, tube string, delay uint64) error
+}
+
+// Observer is notified of each command processed, with the status of the response, such as
+// INSERTED or NOT_FOUND, and the time taken including any time spent blocked. Unrecognised commands
+// are reported as "unknown". Calls may be made concurrently from multiple connections.
+type Observer interface {
+	ObserveCommand(cmd string, status string, duration time.Duration)
+}
+
+// Authenticator may be implemented by a Handler to support the "auth <user> <token>" extension
+// command. Until Authenticated reports true, all other commands except quit are rejected with
+// AUTH_REQUIRED. Auth should return ErrAuthFailed for rejected credentials.
+type Authenticator interface {
+	Auth(ctx context.Context, user string, token string) error
+
+	Authenticated() bool
+}
+
+func newConn(s *Server, rwc net.Conn) *Conn {
+	ctx, cancel := context.WithCancel(s.ctx)
+	id := s.nextConnID.Add(1)
+
+	c := &Conn{
+		logger: s.logger.With("conn", id),
+		server: s,
+		id:     id,
+		since:  time.Now(),
+		ctx:    ctx,
+		cancel: cancel,
+		rwc:    rwc,
+		w:      &deadlineWriter{conn: rwc, timeout: s.writeTimeout},
+	}
+
+	c.reader = bufio.NewReader(&countingReader{r: rwc, n: &c.bytesIn})
+
+	return c
+}
+
+// blockingContext derives the context of a command that may block indefinitely, which is also
+// cancelled as soon as the server begins shutting down.
+func (c *Conn) blockingContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(c.server.stopping, cancel)
+
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+func (c *Conn) Addr() net.Addr {
+	return c.rwc.RemoteAddr()
+}
+
+// RemoteIP returns the IP address of the client, or an invalid address for connections not made
+// over IP.
+func (c *Conn) RemoteIP() netip.Addr {
+	tcpAddr, ok := c.rwc.RemoteAddr().(*net.TCPAddr)
+	if !ok {
+		return netip.Addr{}
+	}
+
+	return tcpAddr.AddrPort().Addr().Unmap()
+}
+
+// TLSState returns the state of the TLS connection, if the connection uses TLS. The handshake has
+// already completed by the time the Factory is called, so PeerCertificates holds any verified
+// client certificate.
+func (c *Conn) TLSState() (tls.ConnectionState, bool) {
+	tlsConn, ok := c.rwc.(*tls.Conn)
+	if !ok {
+		return tls.ConnectionState{}, false
+	}
+
+	return tlsConn.ConnectionState(), true
+}
+
+func (c *Conn) handshake(tlsConn *tls.Conn) error {
+	ctx, cancel := context.WithTimeout(c.ctx, handshakeTimeout)
+	defer cancel()
+
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return fmt.Errorf("tls handshake failed: %w", err)
+	}
+
+	state := tlsConn.ConnectionState()
+
+	if len(state.PeerCertificates) > 0 {
+		c.logger = c.logger.With("subject", state.PeerCertificates[0].Subject.String())
+	}
+
+	return nil
+}
+
+func (c *Conn) setState(state connState) {
+	c.state.Store(int32(state))
+}
+
+func (c *Conn) isIdle() bool {
+	return connState(c.state.Load()) == connIdle
+}
+
+func (c *Conn) serve() error {
+	defer c.rwc.Close()
+	defer c.cancel()
+
+	c.logger = c.logger.With("remote", c.rwc.RemoteAddr().String())
+	c.labelConn()
+
+	if tlsConn, ok := c.rwc.(*tls.Conn); ok {
+		if err := c.handshake(tlsConn); err != nil {
+			return err
+		}
+	}
+
+	c.logger.Info("Accepted new beanstalk connection")
+
+	c.handler = c.server.factory(c)
+
+	if closer, ok := c.handler.(io.Closer); ok {
+		defer func() {
+			if err := closer.Close(); err != nil {
+				c.logger.Warn("Failed to close handler", "err", err)
+			}
+		}()
+	}
+
+	for {
+		// Set before marking the connection idle, so that it cannot replace the deadline set by a
+		// concurrent shutdown.
+		if c.server.idleTimeout > 0 {
+			_ = c.rwc.SetReadDeadline(time.Now().Add(c.server.idleTimeout))
+		}
+
+		// Marked idle before checking for shutdown, so that a concurrent shutdown either sees the
+		// connection as idle, or the connection sees the shutdown.
+		c.setState(connIdle)
+
+		if c.server.shuttingDown.Load() {
+			return nil
+		}
+
+		line, err := readFullLine(c.reader, c.server.maxLineLength)
+		if errors.Is(err, errLineTooLong) {
+			c.setState(connActive)
+
+			if err := writeLine(c.w, resBadFormat); err != nil {
+				return err
+			}
+
+			continue
+		} else if err != nil {
+			if c.server.shuttingDown.Load() {
+				return nil
+			}
+
+			if c.disconnected.Load() {
+				c.logger.Info("Client disconnected by server")
+
+				return nil
+			}
+
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				c.server.stats.idleTimeouts.Add(1)
+
+				c.logger.Info("Closing idle connection")
+
+				return nil
+			}
+
+			return fmt.Errorf("read line failed: %w", err)
+		}
+
+		c.setState(connActive)
+
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		c.logged = c.logCommand(fields)
+
+		if err := c.observe(fields); errors.Is(err, errQuit) {
+			c.logger.Info("Client quit")
+
+			return nil
+		} else if errors.Is(err, errStreamEnded) {
+			c.logger.Info("Event subscription ended")
+
+			return nil
+		} else if err != nil && c.disconnected.Load() {
+			c.logger.Info("Client disconnected by server")
+
+			return nil
+		} else if errors.Is(err, errWriteTimeout) {
+			c.server.stats.writeTimeouts.Add(1)
+
+			c.logger.Warn("Closing slow connection, write timed out")
+
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+// observe processes a command within a span, reporting the response and duration to the observer,
+// if any.
+func (c *Conn) observe(fields []string) error {
+	cmd := commandName(fields[0])
+
+	ctx, span := c.server.tracer.Start(
+		c.ctx,
+		"beanstalk "+cmd,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(AttrCommand.String(cmd)),
+	)
+	defer span.End()
+
+	ctx, unlabel := c.labelCommand(ctx, cmd)
+	defer unlabel()
+
+	c.w.status = ""
+	start := time.Now()
+
+	c.command.Store(&activeCommand{name: cmd, started: start})
+	defer c.command.Store(nil)
+
+	err := c.process(ctx, fields)
+
+	if c.w.status != "" {
+		span.SetAttributes(AttrStatus.String(c.w.status))
+
+		if c.server.observer != nil {
+			c.server.observer.ObserveCommand(cmd, c.w.status, time.Since(start))
+		}
+	}
+
+	if err != nil && !errors.Is(err, errQuit) && !errors.Is(err, errStreamEnded) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	return err
+}
+
+// process executes a single command. Malformed commands and handler failures are reported to the
+// client, only errors that leave the connection unusable are returned.
+func (c *Conn) process(ctx context.Context, fields []string) error {
+	cmd := strings.ToLower(fields[0])
+
+	if cmd != cmdQuit && cmd != cmdAuth && !c.authenticated() {
+		return c.rejectUnauthenticated(cmd, fields)
+	}
+
+	switch cmd {
+	case cmdQuit:
+		return errQuit
+
+	case cmdAuth:
+		authenticator, ok := c.handler.(Authenticator)
+		if !ok {
+			return writeLine(c.w, resUnknownCommand)
+		}
+
+		if len(fields) != 3 {
+			return writeLine(c.w, resBadFormat)
+		}
+
+		err := authenticator.Auth(ctx, fields[1], fields[2])
+		if errors.Is(err, ErrAuthFailed) {
+			return writeLine(c.w, resAuthFailed)
+		} else if err != nil {
+			return c.writeError(cmd, err)
+		}
+
+		return writeLine(c.w, resAuthenticated)
+
+	case cmdPut:
+		var pri, delay, ttr, size uint64
+
+		if !parseUints(fields, &pri, &delay, &ttr, &size) {
+			return writeLine(c.w, resBadFormat)
+		}
+
+		if size > uint64(c.server.maxJobSize) {
+			if err := discardBlob(c.reader, size); err != nil {
+				return fmt.Errorf("failed to discard job body: %w", err)
+			}
+
+			return writeLine(c.w, resJobTooBig)
+		}
+
+		data, err := readBlob(c.reader, int(size))
+		if errors.Is(err, MissingLineEnd) {
+			return writeLine(c.w, resExpectedCRLF)
+		} else if err != nil {
+			return fmt.Errorf("failed to read job body: %w", err)
+		}
+
+		setSpanAttrs(ctx, AttrJobBytes.Int(len(data)))
+
+		if c.logged && c.server.logBodies {
+			c.logger.Debug("Read job body", "body", string(data))
+		}
+
+		id, buried, err := c.handler.Put(ctx, pri, delay, ttr, data)
+		if err != nil {
+			return c.writeError(cmd, err)
+		}
+
+		setSpanAttrs(ctx, jobID(id))
+
+		if buried {
+			return writeLine(c.w, resBuriedID, id)
+		}
+
+		return writeLine(c.w, resInserted, id)
+
+	case cmdUse:
+		if len(fields) != 2 || !validTubeName(fields[1]) {
+			return writeLine(c.w, resBadFormat)
+		}
+
+		setSpanAttrs(ctx, AttrTube.String(fields[1]))
+
+		tube, err := c.handler.Use(ctx, fields[1])
+		if err != nil {
+			return c.writeError(cmd, err)
+		}
+
+		return writeLine(c.w, resUsing, tube)
+
+	case cmdReserve, cmdReserveWithTimeout:
+		timeout := int64(-1)
+
+		if cmd == cmdReserveWithTimeout {
+			if len(fields) != 2 {
+				return writeLine(c.w, resBadFormat)
+			}
+
+			parsed, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return writeLine(c.w, resBadFormat)
+			}
+
+			timeout = parsed
+		} else if len(fields) != 1 {
+			return writeLine(c.w, resBadFormat)
+		}
+
+		reserveCtx, cancel := c.blockingContext(ctx)
+		defer cancel()
+
+		id, data, err := c.handler.Reserve(reserveCtx, timeout)
+		if errors.Is(err, ErrReserveTimeout) && timeout >= 0 {
+			return writeLine(c.w, resTimedOut)
+		} else if errors.Is(err, context.Canceled) && c.server.shuttingDown.Load() {
+			return writeLine(c.w, resTimedOut)
+		} else if err != nil {
+			return c.writeError(cmd, err)
+		}
+
+		setSpanAttrs(ctx, jobID(id), AttrJobBytes.Int(len(data)))
+
+		return writeLine(c.w, resReserved, id, len(data), data)
+
+	case cmdReserveJob:
+		var id uint64
+
+		if !parseUints(fields, &id) {
+			return writeLine(c.w, resBadFormat)
+		}
+
+		setSpanAttrs(ctx, jobID(id))
+
+		id, data, err := c.handler.ReserveByID(ctx, id)
+		if err != nil {
+			return c.writeError(cmd, err)
+		}
+
+		setSpanAttrs(ctx, jobID(id), AttrJobBytes.Int(len(data)))
+
+		return writeLine(c.w, resReserved, id, len(data), data)
+
+	case cmdDelete:
+		var id uint64
+
+		if !parseUints(fields, &id) {
+			return writeLine(c.w, resBadFormat)
+		}
+
+		setSpanAttrs(ctx, jobID(id))
+
+		if err := c.handler.Delete(ctx, id); err != nil {
+			return c.writeError(cmd, err)
+		}
+
+		return writeLine(c.w, resDeleted)
+
+	case cmdRelease:
+		var id, pri, delay uint64
+
+		if !parseUints(fields, &id, &pri, &delay) {
+			return writeLine(c.w, resBadFormat)
+		}
+
+		setSpanAttrs(ctx, jobID(id))
+
+		if err := c.handler.Release(ctx, id, pri, delay); err != nil {
+			return c.writeError(cmd, err)
+		}
+
+		return writeLine(c.w, resReleased)
+
+	case cmdBury:
+		var id, pri uint64
+
+		if !parseUints(fields, &id, &pri) {
+			return writeLine(c.w, resBadFormat)
+		}
+
+		setSpanAttrs(ctx, jobID(id))
+
+		if err := c.handler.Bury(ctx, id, pri); err != nil {
+			return c.writeError(cmd, err)
+		}
+
+		return writeLine(c.w, resBuried)
+
+	case cmdTouch:
+		var id uint64
+
+		if !parseUints(fields, &id) {
+			return writeLine(c.w, resBadFormat)
+		}
+
+		setSpanAttrs(ctx, jobID(id))
+
+		if err := c.handler.Touch(ctx, id); err != nil {
+			return c.writeError(cmd, err)
+		}
+
+		return writeLine(c.w, resTouched)
+
+	case cmdWatch:
+		if len(fields) != 2 || !validTubeName(fields[1]) {
+			return writeLine(c.w, resBadFormat)
+		}
+
+		setSpanAttrs(ctx, AttrTube.String(fields[1]))
+
+		count, err := c.handler.Watch(ctx, fields[1])
+		if err != nil {
+			return c.writeError(cmd, err)
+		}
+
+		return writeLine(c.w, resWatching, count)
+
+	case cmdIgnore:
+		if len(fields) != 2 || !validTubeName(fields[1]) {
+			return writeLine(c.w, resBadFormat)
+		}
+
+		setSpanAttrs(ctx, AttrTube.String(fields[1]))
+
+		count, err := c.handler.Ignore(ctx, fields[1])
+		if err != nil {
+			return c.writeError(cmd, err)
+		}
+
+		return writeLine(c.w, resWatching, count)
+
+	case cmdStats:
+		if len(fields) != 1 {
+			return writeLine(c.w, resBadFormat)
+		}
+
+		data := c.server.Stats().yaml()
+
+		return writeLine(c.w, resOK, len(data), data)
+
+	case cmdListClients:
+		manager, ok := c.handler.(ClientManager)
+		if !ok {
+			return writeLine(c.w, resUnknownCommand)
+		}
+
+		if len(fields) != 1 {
+			return writeLine(c.w, resBadFormat)
+		}
+
+		clients, err := manager.ListClients(ctx)
+		if err != nil {
+			return c.writeError(cmd, err)
+		}
+
+		data := clientsYAML(clients)
+
+		return writeLine(c.w, resOK, len(data), data)
+
+	case cmdDisconnectClient:
+		manager, ok := c.handler.(ClientManager)
+		if !ok {
+			return writeLine(c.w, resUnknownCommand)
+		}
+
+		var id uint64
+
+		if !parseUints(fields, &id) {
+			return writeLine(c.w, resBadFormat)
+		}
+
+		if err := manager.DisconnectClient(ctx, id); err != nil {
+			return c.writeError(cmd, err)
+		}
+
+		return writeLine(c.w, resDisconnected)
+
+	case cmdSubscribeEvents:
+		subscriber, ok := c.handler.(EventSubscriber)
+		if !ok {
+			return writeLine(c.w, resUnknownCommand)
+		}
+
+		if len(fields) > 3 {
+			return writeLine(c.w, resBadFormat)
+		}
+
+		var tubes, types []string
+
+		if len(fields) > 1 {
+			tubes = splitList(fields[1])
+		}
+
+		if len(fields) > 2 {
+			types = splitList(fields[2])
+		}
+
+		ctx, cancel := c.blockingContext(ctx)
+		defer cancel()
+
+		events, err := subscriber.SubscribeEvents(ctx, tubes, types)
+		if err != nil {
+			return c.writeError(cmd, err)
+		}
+
+		return c.stream(cancel, events)
+
+	case cmdKick:
+		var bound uint64
+
+		if !parseUints(fields, &bound) {
+			return writeLine(c.w, resBadFormat)
+		}
+
+		count, err := c.handler.Kick(ctx, bound)
+		if err != nil {
+			return c.writeError(cmd, err)
+		}
+
+		return writeLine(c.w, resKickedCount, count)
+
+	case cmdKickJob:
+		var id uint64
+
+		if !parseUints(fields, &id) {
+			return writeLine(c.w, resBadFormat)
+		}
+
+		setSpanAttrs(ctx, jobID(id))
+
+		if err := c.handler.KickJob(ctx, id); err != nil {
+			return c.writeError(cmd, err)
+		}
+
+		return writeLine(c.w, resKicked)
+
+	case cmdPauseTube:
+		var delay uint64
+
+		if len(fields) != 3 || !validTubeName(fields[1]) {
+			return writeLine(c.w, resBadFormat)
+		}
+
+		if !parseUints([]string{cmd, fields[2]}, &delay) {
+			return writeLine(c.w, resBadFormat)
+		}
+
+		setSpanAttrs(ctx, AttrTube.String(fields[1]))
+
+		if err := c.handler.PauseTube(ctx, fields[1], delay); err != nil {
+			return c.writeError(cmd, err)
+		}
+
+		return writeLine(c.w, resPaused)
+
+	default:
+		return writeLine(c.w, resUnknownCommand)
+	}
+}
+
+func (c *Conn) authenticated() bool {
+	authenticator, ok := c.handler.(Authenticator)
+
+	return !ok || authenticator.Authenticated()
+}
+
+// rejectUnauthenticated replies with AUTH_REQUIRED, first discarding the body of a put so that the
+// connection remains usable.
+func (c *Conn) rejectUnauthenticated(cmd string, fields []string) error {
+	var pri, delay, ttr, size uint64
+
+	if cmd == cmdPut && parseUints(fields, &pri, &delay, &ttr, &size) {
+		if err := discardBlob(c.reader, size); err != nil {
+			return fmt.Errorf("failed to discard job body: %w", err)
+		}
+	}
+
+	return writeLine(c.w, resAuthRequired)
+}
+
+// writeError replies with the response matching a handler error, falling back to INTERNAL_ERROR
+// for errors that have no protocol equivalent.
+func (c *Conn) writeError(cmd string, err error) error {
+	// The failure is a result of the connection being closed, so there is no client to reply to
+	if c.disconnected.Load() {
+		return errDisconnected
+	}
+
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return writeLine(c.w, resNotFound)
+	case errors.Is(err, ErrBadFormat):
+		return writeLine(c.w, resBadFormat)
+	case errors.Is(err, ErrNotIgnored):
+		return writeLine(c.w, resNotIgnored)
+	case errors.Is(err, ErrDeadlineSoon):
+		return writeLine(c.w, resDeadlineSoon)
+	case errors.Is(err, ErrDraining):
+		return writeLine(c.w, resDraining)
+	case errors.Is(err, ErrJobTooBig):
+		return writeLine(c.w, resJobTooBig)
+	case errors.Is(err, ErrOutOfMemory):
+		return writeLine(c.w, resOutOfMemory)
+	case errors.Is(err, ErrPermission):
+		return writeLine(c.w, resPermissionDenied)
+	case errors.Is(err, ErrThrottled):
+		return writeLine(c.w, resThrottled)
+	default:
+		c.logger.Error("Command failed", "cmd", cmd, "err", err)
+
+		return writeLine(c.w, resInternalError)
+	}
+}
+
+// deadlineWriter applies a write deadline to each write, when a timeout is set. It also records the
+// status, the first word, of the last response written.
+type deadlineWriter struct {
+	conn    net.Conn
+	timeout time.Duration
+	status  string
+	written atomic.Uint64
+}
+
+func (w *deadlineWriter) Write(p []byte) (int, error) {
+	if i := bytes.IndexAny(p, " \r"); i > 0 {
+		w.status = string(p[:i])
+	}
+
+	if w.timeout <= 0 {
+		n, err := w.conn.Write(p)
+		w.written.Add(uint64(n))
+
+		return n, err
+	}
+
+	if err := w.conn.SetWriteDeadline(time.Now().Add(w.timeout)); err != nil {
+		return 0, err
+	}
+
+	n, err := w.conn.Write(p)
+	w.written.Add(uint64(n))
+
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return n, fmt.Errorf("%w: %w", errWriteTimeout, err)
+	}
+
+	return n, err
+}
+
+// parseUints parses the arguments of a command, which must match the number of outputs.
+func parseUints(fields []string, out ...*uint64) bool {
+	if len(fields) != len(out)+1 {
+		return false
+	}
+
+	for i, o := range out {
+		v, err := strconv.ParseUint(fields[i+1], 10, 64)
+		if err != nil {
+			return false
+		}
+
+		*o = v
+	}
+
+	return true
+}
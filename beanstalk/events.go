@@ -0,0 +1,56 @@
+package beanstalk
+
+import (
+	"context"
+	"iter"
+	"strings"
+	"time"
+)
+
+// EventSubscriber may be implemented by a Handler to support the
+// "subscribe-events [tubes] [types]" extension command, where tubes and types are comma separated
+// lists, or * to match everything. The returned sequence yields encoded events until ctx is
+// cancelled, which happens once the client sends further input or disconnects. Unknown types should
+// be rejected with ErrBadFormat.
+type EventSubscriber interface {
+	SubscribeEvents(ctx context.Context, tubes []string, types []string) (iter.Seq[[]byte], error)
+}
+
+// stream replies with SUBSCRIBED and then writes each event, until the client sends any further
+// input or disconnects. The connection is closed once the stream ends.
+func (c *Conn) stream(cancel context.CancelFunc, events iter.Seq[[]byte]) error {
+	if err := writeLine(c.w, resSubscribed); err != nil {
+		return err
+	}
+
+	// Report the subscription rather than the last event to the observer
+	defer func(status string) {
+		c.w.status = status
+	}(c.w.status)
+
+	// Subscriptions are long lived, so are not subject to the idle timeout
+	_ = c.rwc.SetReadDeadline(time.Time{})
+
+	go func() {
+		_, _ = c.reader.ReadByte()
+
+		cancel()
+	}()
+
+	for data := range events {
+		if err := writeLine(c.w, resEvent, len(data), data); err != nil {
+			return err
+		}
+	}
+
+	return errStreamEnded
+}
+
+// splitList splits a comma separated list, where * matches everything.
+func splitList(s string) []string {
+	if s == "*" {
+		return nil
+	}
+
+	return strings.Split(s, ",")
+}
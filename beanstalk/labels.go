@@ -0,0 +1,46 @@
+package beanstalk
+
+import (
+	"context"
+	"runtime/pprof"
+	"strconv"
+)
+
+// Profiler labels attached to connection goroutines when enabled with WithProfilerLabels. Goroutines
+// started while serving a connection inherit its labels.
+const (
+	LabelConn    = "beanstalk.conn"
+	LabelCommand = "beanstalk.command"
+)
+
+// WithProfilerLabels labels the goroutines serving each connection with the connection id and the
+// command in progress, so that goroutine and CPU profiles can be attributed to clients.
+func WithProfilerLabels() Option {
+	return func(s *Server) {
+		s.pprofLabels = true
+	}
+}
+
+func (c *Conn) labelConn() {
+	if !c.server.pprofLabels {
+		return
+	}
+
+	c.ctx = pprof.WithLabels(c.ctx, pprof.Labels(LabelConn, strconv.FormatUint(c.id, 10)))
+	pprof.SetGoroutineLabels(c.ctx)
+}
+
+// labelCommand labels the current goroutine with cmd, returning a function restoring the connection
+// labels.
+func (c *Conn) labelCommand(ctx context.Context, cmd string) (context.Context, func()) {
+	if !c.server.pprofLabels {
+		return ctx, func() {}
+	}
+
+	ctx = pprof.WithLabels(ctx, pprof.Labels(LabelCommand, cmd))
+	pprof.SetGoroutineLabels(ctx)
+
+	return ctx, func() {
+		pprof.SetGoroutineLabels(c.ctx)
+	}
+}
@@ -0,0 +1,268 @@
+package beanstalk
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/netip"
+	"os"
+	"strings"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/pires/go-proxyproto"
+)
+
+var ErrUnsupportedNetwork = errors.New("unsupported network")
+
+// ListenerConfig describes a single listener of a Server.
+type ListenerConfig struct {
+	// Listener is served instead of opening a new listener, Network and Address are then ignored.
+	Listener net.Listener
+	// Network is either tcp or unix.
+	Network string
+	Address string
+	// TLSConfig serves the listener over TLS when set. The handshake is completed before the Factory
+	// is called, so that Conn.TLSState can be used to authorize clients.
+	TLSConfig *tls.Config
+	// ProxyProtocol parses PROXY protocol v1 and v2 headers, so that Conn.Addr returns the address of
+	// the original client. Headers are only accepted from peers within TrustedProxies, connections
+	// from other peers sending a header are rejected.
+	ProxyProtocol  bool
+	TrustedProxies []netip.Prefix
+	// MaxConnections limits the number of concurrent connections, zero means unlimited.
+	MaxConnections int
+	// FileMode, UID and GID set the permissions and ownership of unix sockets. A zero FileMode and
+	// nil ids leave the defaults in place.
+	FileMode fs.FileMode
+	UID      *int
+	GID      *int
+}
+
+type listener struct {
+	cfg ListenerConfig
+	// raw is the listener before any TLS wrapping
+	raw    net.Listener
+	l      net.Listener
+	active atomic.Int64
+}
+
+func listen(cfg ListenerConfig) (*listener, error) {
+	var (
+		l   net.Listener
+		err error
+	)
+
+	switch {
+	case cfg.Listener != nil:
+		l = cfg.Listener
+		cfg.Network = l.Addr().Network()
+		cfg.Address = l.Addr().String()
+	case cfg.Network == "tcp" || cfg.Network == "tcp4" || cfg.Network == "tcp6":
+		l, err = net.Listen(cfg.Network, cfg.Address)
+	case cfg.Network == "unix":
+		l, err = listenUnix(cfg)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedNetwork, cfg.Network)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s %s: %w", cfg.Network, cfg.Address, err)
+	}
+
+	raw := l
+
+	if cfg.ProxyProtocol {
+		l = &proxyproto.Listener{
+			Listener:          l,
+			Policy:            proxyPolicy(cfg.TrustedProxies),
+			ReadHeaderTimeout: handshakeTimeout,
+		}
+	}
+
+	if cfg.TLSConfig != nil {
+		l = tls.NewListener(l, cfg.TLSConfig)
+	}
+
+	return &listener{
+		cfg: cfg,
+		raw: raw,
+		l:   l,
+	}, nil
+}
+
+func proxyPolicy(trusted []netip.Prefix) proxyproto.PolicyFunc {
+	return func(upstream net.Addr) (proxyproto.Policy, error) {
+		if addr, ok := upstream.(*net.TCPAddr); ok {
+			ip := addr.AddrPort().Addr().Unmap()
+
+			for _, prefix := range trusted {
+				if prefix.Contains(ip) {
+					return proxyproto.USE, nil
+				}
+			}
+		}
+
+		return proxyproto.REJECT, nil
+	}
+}
+
+func listenUnix(cfg ListenerConfig) (net.Listener, error) {
+	if err := removeStaleSocket(cfg.Address); err != nil {
+		return nil, err
+	}
+
+	var (
+		l   net.Listener
+		err error
+	)
+
+	// The mode is applied as the socket is created, so that no client can connect in between
+	withUmask(cfg.FileMode, func() {
+		l, err = net.Listen("unix", cfg.Address)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.FileMode != 0 {
+		if err := os.Chmod(cfg.Address, cfg.FileMode); err != nil {
+			_ = l.Close()
+
+			return nil, fmt.Errorf("failed to set socket mode: %w", err)
+		}
+	}
+
+	if cfg.UID != nil || cfg.GID != nil {
+		if err := os.Lchown(cfg.Address, idOrUnchanged(cfg.UID), idOrUnchanged(cfg.GID)); err != nil {
+			_ = l.Close()
+
+			return nil, fmt.Errorf("failed to set socket owner: %w", err)
+		}
+	}
+
+	return l, nil
+}
+
+// removeStaleSocket removes a socket left behind by an unclean exit. Sockets still accepting
+// connections and other files are never removed.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if err != nil || info.Mode().Type() != fs.ModeSocket {
+		return nil
+	}
+
+	if c, err := net.Dial("unix", path); err == nil {
+		_ = c.Close()
+
+		return fmt.Errorf("%w: socket is in use", syscall.EADDRINUSE)
+	}
+
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to remove stale socket: %w", err)
+	}
+
+	return nil
+}
+
+func idOrUnchanged(id *int) int {
+	if id == nil {
+		return -1
+	}
+
+	return *id
+}
+
+// acquire reserves a connection slot, returning false if the listener is at capacity.
+func (l *listener) acquire() bool {
+	if l.active.Add(1) > int64(l.cfg.MaxConnections) && l.cfg.MaxConnections > 0 {
+		l.active.Add(-1)
+
+		return false
+	}
+
+	return true
+}
+
+func (l *listener) release() {
+	l.active.Add(-1)
+}
+
+func (l *listener) file() (*os.File, error) {
+	return ListenerFile(l.raw)
+}
+
+func (l *listener) handOver() {
+	HandOver(l.raw)
+}
+
+// ListenerFile returns a duplicate of the socket of a TCP or unix listener, for handing over to
+// another process. Once the other process has taken over, HandOver should be called so that unix
+// sockets are not removed when the listener is closed.
+func ListenerFile(l net.Listener) (*os.File, error) {
+	switch raw := l.(type) {
+	case *net.TCPListener:
+		return raw.File()
+	case *net.UnixListener:
+		return raw.File()
+	default:
+		return nil, fmt.Errorf("%w: cannot hand over %T", ErrUnsupportedNetwork, l)
+	}
+}
+
+// HandOver leaves the socket file of a unix listener in place when the listener is closed, as it
+// is now owned by another process. Other listeners are unaffected.
+func HandOver(l net.Listener) {
+	if raw, ok := l.(*net.UnixListener); ok {
+		raw.SetUnlinkOnClose(false)
+	}
+}
+
+// AddrMatches reports whether an open listener address satisfies the network and address, as
+// when matching inherited listeners.
+func AddrMatches(network string, address string, addr net.Addr) bool {
+	return addrMatches(ListenerConfig{Network: network, Address: address}, addr)
+}
+
+// addrMatches reports whether an open listener address satisfies a listener config, treating
+// unspecified hosts as equal to each other.
+func addrMatches(cfg ListenerConfig, addr net.Addr) bool {
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		if !strings.HasPrefix(cfg.Network, "tcp") {
+			return false
+		}
+
+		want, err := net.ResolveTCPAddr(cfg.Network, cfg.Address)
+		if err != nil || want.Port != a.Port {
+			return false
+		}
+
+		if want.IP == nil || want.IP.IsUnspecified() {
+			return a.IP == nil || a.IP.IsUnspecified()
+		}
+
+		return want.IP.Equal(a.IP)
+	case *net.UnixAddr:
+		return cfg.Network == "unix" && cfg.Address == a.Name
+	default:
+		return false
+	}
+}
+
+// peerAddr returns the address of the directly connected peer, without waiting for a PROXY
+// protocol header to arrive.
+func peerAddr(rwc net.Conn) net.Addr {
+	if tlsConn, ok := rwc.(*tls.Conn); ok {
+		rwc = tlsConn.NetConn()
+	}
+
+	if proxyConn, ok := rwc.(*proxyproto.Conn); ok {
+		return proxyConn.Raw().RemoteAddr()
+	}
+
+	return rwc.RemoteAddr()
+}
@@ -0,0 +1,82 @@
+package beanstalk
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const redacted = "[REDACTED]"
+
+// WithLogBodies includes job bodies in debug logs. Bodies are omitted by default, as they may
+// contain sensitive data.
+func WithLogBodies() Option {
+	return func(s *Server) {
+		s.logBodies = true
+	}
+}
+
+// WithLogSampling limits the debug logging of commands. Within each interval, the first commands of
+// each type are logged, and then only every thereafter-th command. A thereafter of zero drops the
+// remaining commands of the interval.
+func WithLogSampling(interval time.Duration, first int, thereafter int) Option {
+	return func(s *Server) {
+		s.logSampler = &logSampler{
+			interval:   interval,
+			first:      uint64(first),
+			thereafter: uint64(thereafter),
+			counts:     make(map[string]uint64),
+		}
+	}
+}
+
+type logSampler struct {
+	interval   time.Duration
+	first      uint64
+	thereafter uint64
+
+	mu     sync.Mutex
+	start  time.Time
+	counts map[string]uint64
+}
+
+func (s *logSampler) allow(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now := time.Now(); now.Sub(s.start) >= s.interval {
+		s.start = now
+		clear(s.counts)
+	}
+
+	s.counts[key]++
+	n := s.counts[key]
+
+	if n <= s.first {
+		return true
+	}
+
+	return s.thereafter > 0 && (n-s.first)%s.thereafter == 0
+}
+
+// logCommand logs a command at debug level, subject to sampling, with credentials redacted. It
+// reports whether the command was logged, so that its body is logged along with it.
+func (c *Conn) logCommand(fields []string) bool {
+	if !c.logger.Enabled(c.ctx, slog.LevelDebug) {
+		return false
+	}
+
+	cmd := commandName(fields[0])
+
+	if c.server.logSampler != nil && !c.server.logSampler.allow(cmd) {
+		return false
+	}
+
+	if cmd == cmdAuth && len(fields) > 2 {
+		fields = append(fields[:2:2], redacted)
+	}
+
+	c.logger.Debug("Read command", "cmd", cmd, "args", fields[1:])
+
+	return true
+}
@@ -0,0 +1,192 @@
+package beanstalk
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strings"
+)
+
+const (
+	cmdQuit               = "quit"
+	cmdPut                = "put"
+	cmdUse                = "use"
+	cmdReserve            = "reserve"
+	cmdReserveWithTimeout = "reserve-with-timeout"
+	cmdReserveJob         = "reserve-job"
+	cmdDelete             = "delete"
+	cmdRelease            = "release"
+	cmdBury               = "bury"
+	cmdTouch              = "touch"
+	cmdWatch              = "watch"
+	cmdIgnore             = "ignore"
+	cmdAuth               = "auth"
+	cmdKick               = "kick"
+	cmdKickJob            = "kick-job"
+	cmdPauseTube          = "pause-tube"
+	cmdStats              = "stats"
+	cmdListClients        = "list-clients"
+	cmdDisconnectClient   = "disconnect-client"
+	cmdSubscribeEvents    = "subscribe-events"
+	endLine               = "\r\n"
+	resInternalError      = "INTERNAL_ERROR" + endLine
+	resOutOfMemory        = "OUT_OF_MEMORY" + endLine
+	resBadFormat          = "BAD_FORMAT" + endLine
+	resExpectedCRLF       = "EXPECTED_CRLF" + endLine
+	resJobTooBig          = "JOB_TOO_BIG" + endLine
+	resUnknownCommand     = "UNKNOWN_COMMAND" + endLine
+	resInserted           = "INSERTED %d" + endLine
+	resBuriedID           = "BURIED %d" + endLine
+	resUsing              = "USING %s" + endLine
+	resReserved           = "RESERVED %d %d" + endLine + "%s" + endLine
+	resTimedOut           = "TIMED_OUT" + endLine
+	resDeadlineSoon       = "DEADLINE_SOON" + endLine
+	resNotFound           = "NOT_FOUND" + endLine
+	resDeleted            = "DELETED" + endLine
+	resReleased           = "RELEASED" + endLine
+	resBuried             = "BURIED" + endLine
+	resTouched            = "TOUCHED" + endLine
+	resWatching           = "WATCHING %d" + endLine
+	resNotIgnored         = "NOT_IGNORED" + endLine
+	resDraining           = "DRAINING" + endLine
+	resAuthenticated      = "AUTHENTICATED" + endLine
+	resAuthFailed         = "AUTH_FAILED" + endLine
+	resAuthRequired       = "AUTH_REQUIRED" + endLine
+	resKickedCount        = "KICKED %d" + endLine
+	resKicked             = "KICKED" + endLine
+	resPaused             = "PAUSED" + endLine
+	resPermissionDenied   = "PERMISSION_DENIED" + endLine
+	resOK                 = "OK %d" + endLine + "%s" + endLine
+	resThrottled          = "THROTTLED" + endLine
+	resDisconnected       = "DISCONNECTED" + endLine
+	resSubscribed         = "SUBSCRIBED" + endLine
+	resEvent              = "EVENT %d" + endLine + "%s" + endLine
+)
+
+var commands = []string{
+	cmdQuit,
+	cmdPut,
+	cmdUse,
+	cmdReserve,
+	cmdReserveWithTimeout,
+	cmdReserveJob,
+	cmdDelete,
+	cmdRelease,
+	cmdBury,
+	cmdTouch,
+	cmdWatch,
+	cmdIgnore,
+	cmdAuth,
+	cmdKick,
+	cmdKickJob,
+	cmdPauseTube,
+	cmdStats,
+	cmdListClients,
+	cmdDisconnectClient,
+	cmdSubscribeEvents,
+}
+
+// commandName normalises a command for reporting, so that arbitrary client input is not used as
+// a metric label.
+func commandName(cmd string) string {
+	cmd = strings.ToLower(cmd)
+
+	if slices.Contains(commands, cmd) {
+		return cmd
+	}
+
+	return "unknown"
+}
+
+const (
+	// DefaultMaxJobSize matches the default job size limit of beanstalkd.
+	DefaultMaxJobSize = 65535
+	// DefaultMaxLineLength matches the command line buffer size of beanstalkd, including the line end.
+	DefaultMaxLineLength = 224
+	maxTubeNameLength    = 200
+	tubeNameChars        = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-+/;.$_()"
+)
+
+var (
+	MissingLineEnd = errors.New("expected crlf")
+	errLineTooLong = errors.New("line too long")
+)
+
+// readFullLine reads a single line of at most maxLength bytes, including the line end. Longer lines
+// are consumed in full before errLineTooLong is returned, so that the reader remains usable.
+func readFullLine(reader *bufio.Reader, maxLength int) (string, error) {
+	var buf strings.Builder
+
+	tooLong := false
+
+	for {
+		l, more, err := reader.ReadLine()
+		if err != nil {
+			return "", err
+		}
+
+		if !tooLong && buf.Len()+len(l)+len(endLine) > maxLength {
+			tooLong = true
+		}
+
+		if !tooLong {
+			buf.Write(l)
+		}
+
+		if !more {
+			break
+		}
+	}
+
+	if tooLong {
+		return "", errLineTooLong
+	}
+
+	return buf.String(), nil
+}
+
+func discardBlob(reader *bufio.Reader, size uint64) error {
+	n := int64(min(size, math.MaxInt64-uint64(len(endLine)))) + int64(len(endLine))
+
+	_, err := io.CopyN(io.Discard, reader, n)
+
+	return err
+}
+
+func validTubeName(name string) bool {
+	if len(name) == 0 || len(name) > maxTubeNameLength || name[0] == '-' {
+		return false
+	}
+
+	for _, r := range name {
+		if !strings.ContainsRune(tubeNameChars, r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func readBlob(reader *bufio.Reader, size int) ([]byte, error) {
+	data := make([]byte, size+2)
+
+	_, err := io.ReadFull(reader, data)
+	if err != nil {
+		return nil, err
+	}
+
+	if !slices.Equal(data[size:], []byte(endLine)) {
+		return nil, MissingLineEnd
+	}
+
+	return data[0:size], nil
+}
+
+func writeLine(writer io.Writer, cmd string, args ...any) error {
+	_, err := fmt.Fprintf(writer, cmd, args...)
+
+	return err
+}
@@ -0,0 +1,528 @@
+package beanstalk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/netip"
+	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	DefaultAddress   = ":11300"
+	handshakeTimeout = 10 * time.Second
+)
+
+type Option func(s *Server)
+
+// WithLogger sets the logger used by the server and its connections. Defaults to slog.Default.
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *Server) {
+		s.logger = logger
+	}
+}
+
+// WithListener adds a listener to the server. Can be repeated to serve on multiple addresses.
+// If no listeners are added, the server listens on DefaultAddress.
+func WithListener(cfg ListenerConfig) Option {
+	return func(s *Server) {
+		s.listenerCfgs = append(s.listenerCfgs, cfg)
+	}
+}
+
+// WithNetListener adds an already open listener, such as one passed in by a parent process.
+func WithNetListener(l net.Listener) Option {
+	return WithListener(ListenerConfig{
+		Listener: l,
+	})
+}
+
+// WithInheritedListeners provides open listeners, typically from socket activation, to be used
+// in place of opening a configured listener with the same address. Listeners that do not match
+// any configured listener are served with default settings.
+func WithInheritedListeners(ls ...net.Listener) Option {
+	return func(s *Server) {
+		s.inherited = append(s.inherited, ls...)
+	}
+}
+
+// WithAddress adds a TCP listener on the given address.
+func WithAddress(address string) Option {
+	return WithListener(ListenerConfig{
+		Network: "tcp",
+		Address: address,
+	})
+}
+
+// WithMaxJobSize sets the maximum job body size in bytes. Larger jobs are rejected with
+// JOB_TOO_BIG. Defaults to DefaultMaxJobSize.
+func WithMaxJobSize(size int) Option {
+	return func(s *Server) {
+		s.maxJobSize = size
+	}
+}
+
+// WithMaxLineLength sets the maximum length of a command line, including the line end. Longer
+// lines are rejected with BAD_FORMAT. Defaults to DefaultMaxLineLength.
+func WithMaxLineLength(length int) Option {
+	return func(s *Server) {
+		s.maxLineLength = length
+	}
+}
+
+// WithMaxConnections limits the number of concurrent connections across all listeners. Further
+// connections are closed immediately. Zero means unlimited.
+func WithMaxConnections(n int) Option {
+	return func(s *Server) {
+		s.maxConns = n
+	}
+}
+
+// WithMaxConnectionsPerIP limits the number of concurrent connections from a single client IP.
+// Zero means unlimited. Connections not made over IP, such as unix sockets, are not limited.
+func WithMaxConnectionsPerIP(n int) Option {
+	return func(s *Server) {
+		s.maxConnsPerIP = n
+	}
+}
+
+// WithIdleTimeout closes connections that do not send a command within the timeout. Clients
+// blocked in a reserve are not considered idle. Zero disables the timeout.
+func WithIdleTimeout(timeout time.Duration) Option {
+	return func(s *Server) {
+		s.idleTimeout = timeout
+	}
+}
+
+// WithWriteTimeout closes connections that do not accept a response within the timeout, so that
+// slow readers cannot hold on to server resources. Zero disables the timeout.
+func WithWriteTimeout(timeout time.Duration) Option {
+	return func(s *Server) {
+		s.writeTimeout = timeout
+	}
+}
+
+// WithObserver reports every processed command to o.
+func WithObserver(o Observer) Option {
+	return func(s *Server) {
+		s.observer = o
+	}
+}
+
+// WithTracerProvider sets the provider used to trace commands. Defaults to the global provider.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(s *Server) {
+		s.tracer = tp.Tracer(tracerName)
+	}
+}
+
+type Server struct {
+	logger        *slog.Logger
+	listenerCfgs  []ListenerConfig
+	listeners     []*listener
+	inherited     []net.Listener
+	maxJobSize    int
+	maxLineLength int
+	maxConns      int
+	maxConnsPerIP int
+	idleTimeout   time.Duration
+	writeTimeout  time.Duration
+	factory       Factory
+	observer      Observer
+	tracer        trace.Tracer
+	pprofLabels   bool
+	logBodies     bool
+	logSampler    *logSampler
+	shuttingDown  atomic.Bool
+	ctx           context.Context
+	cancel        context.CancelFunc
+	stopping      context.Context
+	stopBlocking  context.CancelFunc
+	mu            sync.Mutex
+	conns         map[*Conn]struct{}
+	nextConnID    atomic.Uint64
+	perIP         map[netip.Addr]int
+	wg            sync.WaitGroup
+	stats         serverStats
+}
+
+// NewServer creates a beanstalk protocol server, using factory to create a Handler for each
+// accepted connection. The listener is opened immediately, but connections are only accepted
+// once Serve is called.
+func NewServer(factory Factory, opts ...Option) (*Server, error) {
+	s := &Server{
+		logger:        slog.Default(),
+		maxJobSize:    DefaultMaxJobSize,
+		maxLineLength: DefaultMaxLineLength,
+		factory:       factory,
+		conns:         make(map[*Conn]struct{}),
+		perIP:         make(map[netip.Addr]int),
+		tracer:        otel.GetTracerProvider().Tracer(tracerName),
+	}
+
+	s.stats.started = time.Now()
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if len(s.listenerCfgs) == 0 && len(s.inherited) == 0 {
+		WithAddress(DefaultAddress)(s)
+	}
+
+	for _, cfg := range s.listenerCfgs {
+		if cfg.Listener == nil {
+			cfg.Listener = s.takeInherited(cfg)
+		}
+
+		l, err := listen(cfg)
+		if err != nil {
+			s.closeListeners()
+
+			return nil, err
+		}
+
+		s.listeners = append(s.listeners, l)
+	}
+
+	// Inherited listeners without matching config are still served, rather than left dangling
+	for _, inherited := range s.inherited {
+		s.logger.Warn("Serving inherited listener without matching config", "addr", inherited.Addr().String())
+
+		l, err := listen(ListenerConfig{Listener: inherited})
+		if err != nil {
+			s.closeListeners()
+
+			return nil, err
+		}
+
+		s.listeners = append(s.listeners, l)
+	}
+
+	s.inherited = nil
+
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.stopping, s.stopBlocking = context.WithCancel(s.ctx)
+
+	return s, nil
+}
+
+// Serve accepts connections on all listeners, until the server is shut down or a listener fails.
+func (s *Server) Serve() error {
+	errCh := make(chan error, len(s.listeners))
+
+	for _, l := range s.listeners {
+		go func() {
+			errCh <- s.serveListener(l)
+		}()
+	}
+
+	var errs []error
+
+	for range s.listeners {
+		if err := <-errCh; err != nil {
+			errs = append(errs, err)
+
+			// Stop accepting on the remaining listeners, existing connections are left intact
+			s.closeListeners()
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (s *Server) serveListener(l *listener) error {
+	s.logger.Info(
+		"Listening for beanstalk connections",
+		"network", l.cfg.Network,
+		"addr", l.l.Addr().String(),
+		"tls", l.cfg.TLSConfig != nil,
+	)
+
+	for {
+		rwc, err := l.l.Accept()
+		if err != nil {
+			if s.shuttingDown.Load() || errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+
+			return fmt.Errorf("failed to accept connection: %w", err)
+		}
+
+		if !l.acquire() {
+			s.stats.rejected.Add(1)
+
+			s.logger.Warn("Rejected connection, listener at capacity", "remote", peerAddr(rwc), "addr", l.l.Addr().String())
+
+			_ = rwc.Close()
+
+			continue
+		}
+
+		if !s.acquire() {
+			l.release()
+
+			s.stats.rejected.Add(1)
+
+			s.logger.Warn("Rejected connection, server at capacity", "remote", peerAddr(rwc))
+
+			_ = rwc.Close()
+
+			continue
+		}
+
+		c := newConn(s, rwc)
+
+		if !s.trackConn(c) {
+			s.release()
+			l.release()
+
+			_ = rwc.Close()
+
+			return nil
+		}
+
+		go func() {
+			defer l.release()
+			defer s.release()
+			defer s.untrackConn(c)
+
+			// Checked here rather than in the accept loop, as resolving the address of a PROXY
+			// protocol connection requires reading its header.
+			ip := c.RemoteIP()
+
+			if !s.acquireIP(ip) {
+				s.stats.rejectedPerIP.Add(1)
+
+				c.logger.Warn("Rejected connection, too many connections from address", "remote", ip)
+
+				_ = rwc.Close()
+
+				return
+			}
+
+			defer s.releaseIP(ip)
+
+			s.stats.total.Add(1)
+
+			if err := c.serve(); err != nil {
+				c.logger.Warn("Error while serving connection", "err", err)
+			}
+		}()
+	}
+}
+
+// acquire reserves a server wide connection slot, returning false if the server is at capacity.
+func (s *Server) acquire() bool {
+	if s.stats.current.Add(1) > int64(s.maxConns) && s.maxConns > 0 {
+		s.stats.current.Add(-1)
+
+		return false
+	}
+
+	return true
+}
+
+func (s *Server) release() {
+	s.stats.current.Add(-1)
+}
+
+// acquireIP reserves a connection slot for the client address, returning false if the address is
+// at capacity.
+func (s *Server) acquireIP(ip netip.Addr) bool {
+	if s.maxConnsPerIP <= 0 || !ip.IsValid() {
+		return true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.perIP[ip] >= s.maxConnsPerIP {
+		return false
+	}
+
+	s.perIP[ip]++
+
+	return true
+}
+
+func (s *Server) releaseIP(ip netip.Addr) {
+	if s.maxConnsPerIP <= 0 || !ip.IsValid() {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.perIP[ip]--; s.perIP[ip] <= 0 {
+		delete(s.perIP, ip)
+	}
+}
+
+// Stats returns a snapshot of the server counters.
+func (s *Server) Stats() Stats {
+	return s.stats.snapshot()
+}
+
+func (s *Server) trackConn(c *Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.shuttingDown.Load() {
+		return false
+	}
+
+	s.conns[c] = struct{}{}
+	s.wg.Add(1)
+
+	return true
+}
+
+func (s *Server) untrackConn(c *Conn) {
+	s.mu.Lock()
+	delete(s.conns, c)
+	s.mu.Unlock()
+
+	s.wg.Done()
+}
+
+// Addr returns the address of the first listener.
+func (s *Server) Addr() net.Addr {
+	return s.listeners[0].l.Addr()
+}
+
+// ListenerFiles returns duplicates of the underlying listener sockets, for handing over to another
+// process, in the same order as Addrs. The caller must close the returned files, and call HandOver
+// once the other process has taken over.
+func (s *Server) ListenerFiles() ([]*os.File, error) {
+	files := make([]*os.File, 0, len(s.listeners))
+
+	for _, l := range s.listeners {
+		f, err := l.file()
+		if err != nil {
+			for _, f := range files {
+				_ = f.Close()
+			}
+
+			return nil, err
+		}
+
+		files = append(files, f)
+	}
+
+	return files, nil
+}
+
+// HandOver leaves the socket files of unix listeners in place when the server is closed, as they
+// are now owned by another process.
+func (s *Server) HandOver() {
+	for _, l := range s.listeners {
+		l.handOver()
+	}
+}
+
+func (s *Server) takeInherited(cfg ListenerConfig) net.Listener {
+	for i, l := range s.inherited {
+		if addrMatches(cfg, l.Addr()) {
+			s.inherited = slices.Delete(s.inherited, i, i+1)
+
+			return l
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) Addrs() []net.Addr {
+	addrs := make([]net.Addr, 0, len(s.listeners))
+
+	for _, l := range s.listeners {
+		addrs = append(addrs, l.l.Addr())
+	}
+
+	return addrs
+}
+
+// Shutdown gracefully stops the server. The listener is closed, idle connections are closed,
+// blocked reserves are answered with TIMED_OUT and all other commands are allowed to complete,
+// their contexts only being cancelled once ctx is done.
+// Shutdown waits for all connections to finish, or until ctx is done, at which point any
+// remaining connections are forcibly closed and the context error is returned.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.beginShutdown()
+
+	s.mu.Lock()
+
+	for c := range s.conns {
+		if c.isIdle() {
+			// Interrupts the pending read, the connection then observes the shutdown
+			_ = c.rwc.SetReadDeadline(time.Now())
+		}
+	}
+
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		s.cancel()
+
+		return nil
+	case <-ctx.Done():
+		s.cancel()
+		s.closeConns()
+
+		<-done
+
+		return ctx.Err()
+	}
+}
+
+// Close immediately stops the server, closing the listener and all connections.
+func (s *Server) Close() {
+	s.beginShutdown()
+	s.cancel()
+	s.closeConns()
+}
+
+// beginShutdown stops accepting connections and ends blocking commands. The contexts of other
+// commands are only cancelled once connections are forcibly closed.
+func (s *Server) beginShutdown() {
+	s.mu.Lock()
+	s.shuttingDown.Store(true)
+	s.mu.Unlock()
+
+	s.stopBlocking()
+
+	s.closeListeners()
+}
+
+func (s *Server) closeListeners() {
+	for _, l := range s.listeners {
+		_ = l.l.Close()
+	}
+}
+
+func (s *Server) closeConns() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for c := range s.conns {
+		_ = c.rwc.Close()
+	}
+}
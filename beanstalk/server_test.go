@@ -0,0 +1,675 @@
+package beanstalk_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	bc "github.com/beanstalkd/go-beanstalk"
+	"github.com/csnewman/beanbridge/beanstalk"
+	"github.com/csnewman/beanbridge/internal/mocks"
+	"github.com/csnewman/beanbridge/internal/testutils"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPut(t *testing.T) {
+	t.Parallel()
+
+	handler := mocks.NewMockBeanstalkHandler(t)
+
+	testutils.Server(t, func(conn *beanstalk.Conn) beanstalk.Handler {
+		return handler
+	}, func(t *testing.T, s *beanstalk.Server) {
+		c, err := bc.Dial(s.Addr().Network(), s.Addr().String())
+		require.NoError(t, err, "Client should connect")
+
+		handler.EXPECT().
+			Put(mock.Anything, uint64(1), uint64(0), uint64(120), []byte("hello")).
+			Return(123, false, nil)
+
+		id, err := c.Put([]byte("hello"), 1, 0, 120*time.Second)
+		require.NoError(t, err, "Put should not error")
+		require.Equal(t, uint64(123), id, "Put should return id 123")
+
+		handler.EXPECT().
+			Put(mock.Anything, uint64(101), uint64(102), uint64(103), []byte("world")).
+			Return(234, true, nil)
+
+		id, err = c.Put([]byte("world"), 101, 102*time.Second, 103*time.Second)
+		require.ErrorContains(t, err, "BURIED 234", "Put should return buried")
+
+		handler.EXPECT().
+			Put(mock.Anything, uint64(101), uint64(102), uint64(103), []byte("error")).
+			Return(0, false, errors.New("example"))
+
+		id, err = c.Put([]byte("error"), 101, 102*time.Second, 103*time.Second)
+		require.ErrorContains(t, err, "internal error", "Put should return error")
+
+		handler.EXPECT().
+			Put(mock.Anything, uint64(1), uint64(0), uint64(120), []byte("drain")).
+			Return(0, false, beanstalk.ErrDraining)
+
+		_, err = c.Put([]byte("drain"), 1, 0, 120*time.Second)
+		require.ErrorIs(t, err, bc.ErrDraining, "Put should return draining")
+	})
+}
+
+func TestUse(t *testing.T) {
+	t.Parallel()
+
+	handler := mocks.NewMockBeanstalkHandler(t)
+
+	testutils.Server(t, func(conn *beanstalk.Conn) beanstalk.Handler {
+		return handler
+	}, func(t *testing.T, s *beanstalk.Server) {
+		c, err := bc.Dial(s.Addr().Network(), s.Addr().String())
+		require.NoError(t, err, "Client should connect")
+
+		handler.EXPECT().
+			Put(mock.Anything, uint64(1), uint64(0), uint64(120), []byte("hello")).
+			Return(123, false, nil)
+
+		handler.EXPECT().
+			Use(mock.Anything, "tube1").
+			Return("tube1", nil)
+
+		t1 := bc.NewTube(c, "tube1")
+
+		id, err := t1.Put([]byte("hello"), 1, 0, 120*time.Second)
+		require.NoError(t, err, "Put should not error")
+		require.Equal(t, uint64(123), id, "Put should return id 123")
+
+		t2 := bc.NewTube(c, "tube2")
+
+		handler.EXPECT().
+			Use(mock.Anything, "tube2").
+			Return("", errors.New("example"))
+
+		id, err = t2.Put([]byte("hello"), 1, 0, 120*time.Second)
+		require.ErrorContains(t, err, "internal error", "Put should return error")
+	})
+}
+
+func TestShutdown(t *testing.T) {
+	t.Parallel()
+
+	handler := mocks.NewMockBeanstalkHandler(t)
+
+	testutils.Server(t, func(conn *beanstalk.Conn) beanstalk.Handler {
+		return handler
+	}, func(t *testing.T, s *beanstalk.Server) {
+		idle, err := bc.Dial(s.Addr().Network(), s.Addr().String())
+		require.NoError(t, err, "Client should connect")
+
+		blocked, err := bc.Dial(s.Addr().Network(), s.Addr().String())
+		require.NoError(t, err, "Client should connect")
+
+		reserving := make(chan struct{})
+
+		handler.EXPECT().
+			Reserve(mock.Anything, int64(3600)).
+			RunAndReturn(func(ctx context.Context, _ int64) (uint64, []byte, error) {
+				close(reserving)
+
+				<-ctx.Done()
+
+				return 0, nil, ctx.Err()
+			})
+
+		reserveErr := make(chan error, 1)
+
+		go func() {
+			_, _, err := blocked.Reserve(time.Hour)
+			reserveErr <- err
+		}()
+
+		<-reserving
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		require.NoError(t, s.Shutdown(ctx), "Shutdown should not error")
+
+		require.ErrorIs(t, <-reserveErr, bc.ErrTimeout, "Blocked reserve should time out")
+
+		_, err = idle.Put([]byte("hello"), 1, 0, 120*time.Second)
+		require.Error(t, err, "Idle connection should be closed")
+	})
+}
+
+func TestShutdownInFlight(t *testing.T) {
+	t.Parallel()
+
+	handler := mocks.NewMockBeanstalkHandler(t)
+
+	testutils.Server(t, func(conn *beanstalk.Conn) beanstalk.Handler {
+		return handler
+	}, func(t *testing.T, s *beanstalk.Server) {
+		c, err := bc.Dial(s.Addr().Network(), s.Addr().String())
+		require.NoError(t, err, "Client should connect")
+
+		deleting := make(chan struct{})
+		release := make(chan struct{})
+
+		handler.EXPECT().
+			Delete(mock.Anything, uint64(5)).
+			RunAndReturn(func(ctx context.Context, _ uint64) error {
+				close(deleting)
+
+				<-release
+
+				return ctx.Err()
+			})
+
+		deleteErr := make(chan error, 1)
+
+		go func() {
+			deleteErr <- c.Delete(5)
+		}()
+
+		<-deleting
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		shutdownErr := make(chan error, 1)
+
+		go func() {
+			shutdownErr <- s.Shutdown(ctx)
+		}()
+
+		// Give shutdown a chance to begin before the command completes
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+
+		require.NoError(t, <-deleteErr, "In-flight command should complete with its context intact")
+		require.NoError(t, <-shutdownErr, "Shutdown should not error")
+	})
+}
+
+func TestErrorResponses(t *testing.T) {
+	t.Parallel()
+
+	handler := mocks.NewMockBeanstalkHandler(t)
+
+	testutils.Server(t, func(conn *beanstalk.Conn) beanstalk.Handler {
+		return handler
+	}, func(t *testing.T, s *beanstalk.Server) {
+		c, r, roundTrip := dialRoundTrip(t, s)
+
+		roundTrip("put 1 2 abc 5\r\n", "BAD_FORMAT\r\n")
+		roundTrip("delete\r\n", "BAD_FORMAT\r\n")
+		roundTrip("reserve-with-timeout -\r\n", "BAD_FORMAT\r\n")
+		roundTrip("put 1 0 120 5\r\nhelloXX", "EXPECTED_CRLF\r\n")
+
+		handler.EXPECT().
+			Delete(mock.Anything, uint64(5)).
+			Return(errors.New("example"))
+
+		roundTrip("delete 5\r\n", "INTERNAL_ERROR\r\n")
+
+		handler.EXPECT().
+			Put(mock.Anything, uint64(1), uint64(0), uint64(120), []byte("hello")).
+			Return(0, false, beanstalk.ErrJobTooBig)
+
+		roundTrip("put 1 0 120 5\r\nhello\r\n", "JOB_TOO_BIG\r\n")
+
+		handler.EXPECT().
+			Ignore(mock.Anything, "default").
+			Return(0, beanstalk.ErrNotIgnored)
+
+		roundTrip("ignore default\r\n", "NOT_IGNORED\r\n")
+
+		handler.EXPECT().
+			Use(mock.Anything, "tube1").
+			Return("tube1", nil)
+
+		roundTrip("use tube1\r\n", "USING tube1\r\n")
+
+		_, err := c.Write([]byte("quit\r\n"))
+		require.NoError(t, err, "Write should not error")
+
+		_, err = r.ReadString('\n')
+		require.ErrorIs(t, err, io.EOF, "Connection should be closed after quit")
+	})
+}
+
+func TestLimits(t *testing.T) {
+	t.Parallel()
+
+	handler := mocks.NewMockBeanstalkHandler(t)
+
+	testutils.Server(t, func(conn *beanstalk.Conn) beanstalk.Handler {
+		return handler
+	}, func(t *testing.T, s *beanstalk.Server) {
+		_, _, roundTrip := dialRoundTrip(t, s)
+
+		roundTrip("put 1 0 120 11\r\nhello world\r\n", "JOB_TOO_BIG\r\n")
+		roundTrip("use "+strings.Repeat("a", 300)+"\r\n", "BAD_FORMAT\r\n")
+		roundTrip("use -tube\r\n", "BAD_FORMAT\r\n")
+		roundTrip("watch tube!\r\n", "BAD_FORMAT\r\n")
+		roundTrip("ignore "+strings.Repeat("a", 201)+"\r\n", "BAD_FORMAT\r\n")
+
+		handler.EXPECT().
+			Put(mock.Anything, uint64(1), uint64(0), uint64(120), []byte("hello")).
+			Return(1, false, nil)
+
+		roundTrip("put 1 0 120 5\r\nhello\r\n", "INSERTED 1\r\n")
+
+		handler.EXPECT().
+			Use(mock.Anything, "a-Z_0.9+/;$()").
+			Return("a-Z_0.9+/;$()", nil)
+
+		roundTrip("use a-Z_0.9+/;$()\r\n", "USING a-Z_0.9+/;$()\r\n")
+	}, beanstalk.WithMaxJobSize(10), beanstalk.WithMaxLineLength(250))
+}
+
+// dialRoundTrip connects to the server, returning the connection, its reader and a function that
+// sends a request and checks the response line.
+func dialRoundTrip(t *testing.T, s *beanstalk.Server) (net.Conn, *bufio.Reader, func(req string, expected string)) {
+	t.Helper()
+
+	c, err := net.Dial(s.Addr().Network(), s.Addr().String())
+	require.NoError(t, err, "Client should connect")
+
+	t.Cleanup(func() {
+		c.Close()
+	})
+
+	r := bufio.NewReader(c)
+
+	return c, r, func(req string, expected string) {
+		t.Helper()
+
+		_, err := c.Write([]byte(req))
+		require.NoError(t, err, "Write should not error")
+
+		line, err := r.ReadString('\n')
+		require.NoError(t, err, "Read should not error")
+		require.Equal(t, expected, line, "Unexpected response to %q", req)
+	}
+}
+
+func TestLogging(t *testing.T) {
+	t.Parallel()
+
+	handler := mocks.NewMockBeanstalkHandler(t)
+
+	var (
+		mu  sync.Mutex
+		buf bytes.Buffer
+	)
+
+	logger := slog.New(slog.NewJSONHandler(&lockedWriter{mu: &mu, w: &buf}, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	testutils.Server(t, func(conn *beanstalk.Conn) beanstalk.Handler {
+		return handler
+	}, func(t *testing.T, s *beanstalk.Server) {
+		c, err := bc.Dial(s.Addr().Network(), s.Addr().String())
+		require.NoError(t, err, "Client should connect")
+
+		handler.EXPECT().
+			Put(mock.Anything, uint64(1), uint64(0), uint64(120), []byte("secret")).
+			Return(1, false, nil).
+			Times(4)
+
+		for range 4 {
+			_, err := c.Put([]byte("secret"), 1, 0, 120*time.Second)
+			require.NoError(t, err, "Put should not error")
+		}
+
+		require.NoError(t, c.Close())
+	}, beanstalk.WithLogger(logger), beanstalk.WithLogSampling(time.Hour, 2, 0))
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	require.NotContains(t, buf.String(), "secret", "Bodies should not be logged")
+	require.Equal(t, 2, strings.Count(buf.String(), `"msg":"Read command"`), "Commands should be sampled")
+
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if strings.Contains(line, `"msg":"Read command"`) {
+			require.Contains(t, line, `"conn":1`, "Command logs should include the connection id")
+		}
+	}
+}
+
+type lockedWriter struct {
+	mu *sync.Mutex
+	w  io.Writer
+}
+
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.w.Write(p)
+}
+
+func TestConnectionLimits(t *testing.T) {
+	t.Parallel()
+
+	handler := mocks.NewMockBeanstalkHandler(t)
+
+	testutils.Server(t, func(conn *beanstalk.Conn) beanstalk.Handler {
+		return handler
+	}, func(t *testing.T, s *beanstalk.Server) {
+		c1, err := net.Dial(s.Addr().Network(), s.Addr().String())
+		require.NoError(t, err, "Client should connect")
+
+		defer c1.Close()
+
+		r1 := bufio.NewReader(c1)
+
+		_, err = c1.Write([]byte("stats\r\n"))
+		require.NoError(t, err, "Write should not error")
+
+		line, err := r1.ReadString('\n')
+		require.NoError(t, err, "Read should not error")
+		require.True(t, strings.HasPrefix(line, "OK "), "Stats should succeed")
+
+		c2, err := net.Dial(s.Addr().Network(), s.Addr().String())
+		require.NoError(t, err, "Client should connect")
+
+		defer c2.Close()
+
+		_, err = bufio.NewReader(c2).ReadString('\n')
+		require.ErrorIs(t, err, io.EOF, "Second connection from the same address should be closed")
+		require.Equal(t, uint64(1), s.Stats().RejectedConnectionsPerIP)
+
+		// Consumes the remainder of the stats body, returning once the connection is closed
+		_, err = io.ReadAll(r1)
+		require.NoError(t, err, "Idle connection should be closed")
+		require.Equal(t, uint64(1), s.Stats().IdleTimeouts)
+	},
+		beanstalk.WithMaxConnectionsPerIP(1),
+		beanstalk.WithIdleTimeout(200*time.Millisecond),
+	)
+}
+
+func TestTLS(t *testing.T) {
+	t.Parallel()
+
+	handler := mocks.NewMockBeanstalkHandler(t)
+	serverCfg, clientCfg := testutils.TLSConfigs(t, "worker")
+
+	subjects := make(chan string, 1)
+
+	testutils.Server(t, func(conn *beanstalk.Conn) beanstalk.Handler {
+		if state, ok := conn.TLSState(); ok && len(state.PeerCertificates) > 0 {
+			subjects <- state.PeerCertificates[0].Subject.CommonName
+		} else {
+			subjects <- ""
+		}
+
+		return handler
+	}, func(t *testing.T, s *beanstalk.Server) {
+		addr := s.Addrs()[1]
+
+		raw, err := tls.Dial(addr.Network(), addr.String(), clientCfg)
+		require.NoError(t, err, "Client should connect")
+
+		c := bc.NewConn(raw)
+
+		handler.EXPECT().
+			Put(mock.Anything, uint64(1), uint64(0), uint64(120), []byte("hello")).
+			Return(123, false, nil)
+
+		id, err := c.Put([]byte("hello"), 1, 0, 120*time.Second)
+		require.NoError(t, err, "Put should not error")
+		require.Equal(t, uint64(123), id, "Put should return id 123")
+		require.Equal(t, "worker", <-subjects, "Factory should see the client subject")
+	}, beanstalk.WithListener(beanstalk.ListenerConfig{
+		Network:   "tcp",
+		Address:   "127.0.0.1:0",
+		TLSConfig: serverCfg,
+	}))
+}
+
+func TestListeners(t *testing.T) {
+	t.Parallel()
+
+	handler := mocks.NewMockBeanstalkHandler(t)
+	socket := filepath.Join(t.TempDir(), "beanstalk.sock")
+
+	testutils.Server(t, func(conn *beanstalk.Conn) beanstalk.Handler {
+		return handler
+	}, func(t *testing.T, s *beanstalk.Server) {
+		require.Len(t, s.Addrs(), 3, "Server should have three listeners")
+
+		info, err := os.Stat(socket)
+		require.NoError(t, err, "Socket should exist")
+		require.Equal(t, os.FileMode(0o600), info.Mode().Perm(), "Socket mode should be set")
+
+		unix, err := bc.Dial("unix", socket)
+		require.NoError(t, err, "Client should connect over unix socket")
+
+		handler.EXPECT().
+			Put(mock.Anything, uint64(1), uint64(0), uint64(120), []byte("hello")).
+			Return(123, false, nil)
+
+		id, err := unix.Put([]byte("hello"), 1, 0, 120*time.Second)
+		require.NoError(t, err, "Put should not error")
+		require.Equal(t, uint64(123), id, "Put should return id 123")
+
+		limited := s.Addrs()[2]
+
+		first, err := bc.Dial(limited.Network(), limited.String())
+		require.NoError(t, err, "Client should connect")
+
+		handler.EXPECT().
+			Use(mock.Anything, "tube1").
+			Return("tube1", nil)
+
+		_, err = bc.NewTube(first, "tube1").Put([]byte("hello"), 1, 0, 120*time.Second)
+		require.NoError(t, err, "Put should not error")
+
+		second, err := bc.Dial(limited.Network(), limited.String())
+		require.NoError(t, err, "Client should connect")
+
+		_, err = second.Put([]byte("hello"), 1, 0, 120*time.Second)
+		require.Error(t, err, "Connection over the limit should be closed")
+	}, beanstalk.WithListener(beanstalk.ListenerConfig{
+		Network:  "unix",
+		Address:  socket,
+		FileMode: 0o600,
+	}), beanstalk.WithListener(beanstalk.ListenerConfig{
+		Network:        "tcp",
+		Address:        "127.0.0.1:0",
+		MaxConnections: 1,
+	}))
+}
+
+func TestUnixSocket(t *testing.T) {
+	t.Parallel()
+
+	handler := mocks.NewMockBeanstalkHandler(t)
+	socket := filepath.Join(t.TempDir(), "beanstalk.sock")
+
+	// Leave a socket behind, as after an unclean exit
+	stale, err := net.Listen("unix", socket)
+	require.NoError(t, err, "Listen should not error")
+
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	require.NoError(t, stale.Close(), "Close should not error")
+
+	uid := os.Getuid()
+	gid := os.Getgid()
+
+	cfg := beanstalk.ListenerConfig{
+		Network:  "unix",
+		Address:  socket,
+		FileMode: 0o660,
+		UID:      &uid,
+		GID:      &gid,
+	}
+
+	testutils.Server(t, func(conn *beanstalk.Conn) beanstalk.Handler {
+		return handler
+	}, func(t *testing.T, s *beanstalk.Server) {
+		info, err := os.Stat(socket)
+		require.NoError(t, err, "Socket should exist")
+		require.Equal(t, os.FileMode(0o660), info.Mode().Perm(), "Socket mode should be set")
+
+		c, err := bc.Dial("unix", socket)
+		require.NoError(t, err, "Client should connect over the replaced socket")
+		require.NoError(t, c.Close(), "Close should not error")
+
+		_, err = beanstalk.NewServer(func(conn *beanstalk.Conn) beanstalk.Handler {
+			return handler
+		}, beanstalk.WithListener(cfg))
+		require.ErrorIs(t, err, syscall.EADDRINUSE, "Sockets in use should not be replaced")
+
+		_, err = os.Stat(socket)
+		require.NoError(t, err, "Socket in use should remain")
+	}, beanstalk.WithListener(cfg))
+}
+
+func TestHandOver(t *testing.T) {
+	t.Parallel()
+
+	handler := mocks.NewMockBeanstalkHandler(t)
+
+	for _, handOver := range []bool{false, true} {
+		socket := filepath.Join(t.TempDir(), "beanstalk.sock")
+
+		testutils.Server(t, func(conn *beanstalk.Conn) beanstalk.Handler {
+			return handler
+		}, func(t *testing.T, s *beanstalk.Server) {
+			files, err := s.ListenerFiles()
+			require.NoError(t, err, "Listener files should not error")
+
+			for _, f := range files {
+				require.NoError(t, f.Close())
+			}
+
+			if handOver {
+				s.HandOver()
+			}
+		}, beanstalk.WithListener(beanstalk.ListenerConfig{
+			Network: "unix",
+			Address: socket,
+		}))
+
+		_, err := os.Stat(socket)
+
+		if handOver {
+			require.NoError(t, err, "Handed over socket should be left in place")
+		} else {
+			require.ErrorIs(t, err, os.ErrNotExist, "Socket should be removed unless handed over")
+		}
+	}
+}
+
+func TestInheritedListeners(t *testing.T) {
+	t.Parallel()
+
+	handler := mocks.NewMockBeanstalkHandler(t)
+
+	inherited, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err, "Listen should not error")
+
+	testutils.Server(t, func(conn *beanstalk.Conn) beanstalk.Handler {
+		return handler
+	}, func(t *testing.T, s *beanstalk.Server) {
+		require.Len(t, s.Addrs(), 2, "Inherited listener should replace the matching listener")
+		require.Equal(t, inherited.Addr().String(), s.Addrs()[1].String(), "Inherited listener should be used")
+
+		c, err := bc.Dial(inherited.Addr().Network(), inherited.Addr().String())
+		require.NoError(t, err, "Client should connect")
+
+		handler.EXPECT().
+			Put(mock.Anything, uint64(1), uint64(0), uint64(120), []byte("hello")).
+			Return(123, false, nil)
+
+		_, err = c.Put([]byte("hello"), 1, 0, 120*time.Second)
+		require.NoError(t, err, "Put should not error")
+
+		files, err := s.ListenerFiles()
+		require.NoError(t, err, "Listener files should not error")
+		require.Len(t, files, 2, "Listener files should match listeners")
+
+		for _, f := range files {
+			require.NoError(t, f.Close())
+		}
+	}, beanstalk.WithAddress(inherited.Addr().String()), beanstalk.WithInheritedListeners(inherited))
+}
+
+func TestProxyProtocol(t *testing.T) {
+	t.Parallel()
+
+	handler := mocks.NewMockBeanstalkHandler(t)
+	// Buffered for every connection, including those from untrusted peers
+	addrs := make(chan string, 3)
+
+	testutils.Server(t, func(conn *beanstalk.Conn) beanstalk.Handler {
+		addrs <- conn.Addr().String()
+
+		return handler
+	}, func(t *testing.T, s *beanstalk.Server) {
+		trusted := s.Addrs()[1]
+
+		c, err := net.Dial(trusted.Network(), trusted.String())
+		require.NoError(t, err, "Client should connect")
+
+		defer c.Close()
+
+		handler.EXPECT().
+			Use(mock.Anything, "tube1").
+			Return("tube1", nil)
+
+		_, err = c.Write([]byte("PROXY TCP4 192.0.2.1 127.0.0.1 5000 11300\r\nuse tube1\r\n"))
+		require.NoError(t, err, "Write should not error")
+
+		line, err := bufio.NewReader(c).ReadString('\n')
+		require.NoError(t, err, "Read should not error")
+		require.Equal(t, "USING tube1\r\n", line, "Command after the header should be processed")
+		require.Equal(t, "192.0.2.1:5000", <-addrs, "Connection should use the proxied address")
+
+		// Neither listener trusts the client, the latter trusting no peers at all
+		for _, untrusted := range s.Addrs()[2:] {
+			c2, err := net.Dial(untrusted.Network(), untrusted.String())
+			require.NoError(t, err, "Client should connect")
+
+			defer c2.Close()
+
+			_, err = c2.Write([]byte("PROXY TCP4 192.0.2.1 127.0.0.1 5000 11300\r\nuse tube1\r\n"))
+			require.NoError(t, err, "Write should not error")
+
+			_, err = bufio.NewReader(c2).ReadString('\n')
+			require.Error(t, err, "Header from an untrusted peer should be rejected")
+		}
+	}, beanstalk.WithListener(beanstalk.ListenerConfig{
+		Network:       "tcp",
+		Address:       "127.0.0.1:0",
+		ProxyProtocol: true,
+		TrustedProxies: []netip.Prefix{
+			netip.MustParsePrefix("127.0.0.0/8"),
+		},
+	}), beanstalk.WithListener(beanstalk.ListenerConfig{
+		Network:       "tcp",
+		Address:       "127.0.0.1:0",
+		ProxyProtocol: true,
+		TrustedProxies: []netip.Prefix{
+			netip.MustParsePrefix("10.0.0.0/8"),
+		},
+	}), beanstalk.WithListener(beanstalk.ListenerConfig{
+		Network:       "tcp",
+		Address:       "127.0.0.1:0",
+		ProxyProtocol: true,
+	}))
+}
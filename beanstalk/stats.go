@@ -0,0 +1,58 @@
+package beanstalk
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Stats holds server wide counters, as reported by the stats command.
+type Stats struct {
+	CurrentConnections       int64
+	TotalConnections         uint64
+	RejectedConnections      uint64
+	RejectedConnectionsPerIP uint64
+	IdleTimeouts             uint64
+	WriteTimeouts            uint64
+	Uptime                   time.Duration
+}
+
+type serverStats struct {
+	started       time.Time
+	current       atomic.Int64
+	total         atomic.Uint64
+	rejected      atomic.Uint64
+	rejectedPerIP atomic.Uint64
+	idleTimeouts  atomic.Uint64
+	writeTimeouts atomic.Uint64
+}
+
+func (s *serverStats) snapshot() Stats {
+	return Stats{
+		CurrentConnections:       s.current.Load(),
+		TotalConnections:         s.total.Load(),
+		RejectedConnections:      s.rejected.Load(),
+		RejectedConnectionsPerIP: s.rejectedPerIP.Load(),
+		IdleTimeouts:             s.idleTimeouts.Load(),
+		WriteTimeouts:            s.writeTimeouts.Load(),
+		Uptime:                   time.Since(s.started),
+	}
+}
+
+// yaml formats the stats as the YAML dictionary returned by the stats command.
+func (s Stats) yaml() string {
+	var b strings.Builder
+
+	b.WriteString("---\n")
+
+	fmt.Fprintf(&b, "current-connections: %d\n", s.CurrentConnections)
+	fmt.Fprintf(&b, "total-connections: %d\n", s.TotalConnections)
+	fmt.Fprintf(&b, "rejected-connections: %d\n", s.RejectedConnections)
+	fmt.Fprintf(&b, "rejected-connections-per-ip: %d\n", s.RejectedConnectionsPerIP)
+	fmt.Fprintf(&b, "idle-timeouts: %d\n", s.IdleTimeouts)
+	fmt.Fprintf(&b, "write-timeouts: %d\n", s.WriteTimeouts)
+	fmt.Fprintf(&b, "uptime: %d\n", int64(s.Uptime.Seconds()))
+
+	return b.String()
+}
@@ -0,0 +1,27 @@
+package beanstalk
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/csnewman/beanbridge/beanstalk"
+
+// Span attributes recorded for commands, also used by handlers for consistency.
+const (
+	AttrCommand  = attribute.Key("beanstalk.command")
+	AttrStatus   = attribute.Key("beanstalk.status")
+	AttrTube     = attribute.Key("beanstalk.tube")
+	AttrJobID    = attribute.Key("beanstalk.job.id")
+	AttrJobBytes = attribute.Key("beanstalk.job.bytes")
+)
+
+func setSpanAttrs(ctx context.Context, attrs ...attribute.KeyValue) {
+	trace.SpanFromContext(ctx).SetAttributes(attrs...)
+}
+
+func jobID(id uint64) attribute.KeyValue {
+	return AttrJobID.Int64(int64(id))
+}
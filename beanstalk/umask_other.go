@@ -0,0 +1,10 @@
+//go:build !unix
+
+package beanstalk
+
+import "io/fs"
+
+// withUmask runs fn. Platforms without a umask rely on the mode being set once the socket exists.
+func withUmask(_ fs.FileMode, fn func()) {
+	fn()
+}
@@ -0,0 +1,24 @@
+//go:build unix
+
+package beanstalk
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// withUmask runs fn with the process umask denying group and other permissions missing from mode.
+// Owner permissions are left alone, as the umask is process wide. A zero mode leaves the umask
+// unchanged.
+func withUmask(mode fs.FileMode, fn func()) {
+	if mode == 0 {
+		fn()
+
+		return
+	}
+
+	old := syscall.Umask(int(^mode.Perm() & 0o077))
+	defer syscall.Umask(old)
+
+	fn()
+}
@@ -0,0 +1,112 @@
+package bridge
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/csnewman/beanbridge/acl"
+)
+
+const (
+	aclAllow = "allow"
+	aclDeny  = "deny"
+)
+
+// ACLConfig restricts the tube operations each client may perform. Rules are evaluated in order
+// and the first matching rule applies. Requests matching no rule are handled according to Default,
+// which is deny unless set to allow.
+type ACLConfig struct {
+	Default string    `yaml:"default"`
+	Rules   []ACLRule `yaml:"rules"`
+}
+
+// ACLRule matches requests where all non-empty conditions hold. Effect is either allow or deny,
+// Actions are any of use, put, watch, reserve, delete, kick, pause or admin, and Principals and
+// Tubes accept '*' and '?' wildcards. Admin is only matched by rules listing it in Actions.
+type ACLRule struct {
+	Effect     string   `yaml:"effect"`
+	Principals []string `yaml:"principals"`
+	Sources    []string `yaml:"sources"`
+	Actions    []string `yaml:"actions"`
+	Tubes      []string `yaml:"tubes"`
+}
+
+func (c *ACLConfig) validate(field string) error {
+	var errs []error
+
+	if c.Default != "" && c.Default != aclAllow && c.Default != aclDeny {
+		errs = append(errs, fmt.Errorf("%s.default: must be allow or deny", field))
+	}
+
+	for i, r := range c.Rules {
+		if err := r.validate(fmt.Sprintf("%s.rules[%d]", field, i)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (c *ACLConfig) build() (*acl.Policy, error) {
+	policy := &acl.Policy{
+		DefaultAllow: c.Default == aclAllow,
+	}
+
+	for _, r := range c.Rules {
+		rule, err := r.build()
+		if err != nil {
+			return nil, err
+		}
+
+		policy.Rules = append(policy.Rules, rule)
+	}
+
+	return policy, nil
+}
+
+func (r *ACLRule) validate(field string) error {
+	var errs []error
+
+	if r.Effect != aclAllow && r.Effect != aclDeny {
+		errs = append(errs, fmt.Errorf("%s.effect: must be allow or deny", field))
+	}
+
+	if _, err := parsePrefixes(r.Sources); err != nil {
+		errs = append(errs, fmt.Errorf("%s.sources: %w", field, err))
+	}
+
+	rule := acl.Rule{
+		Actions: r.actions(),
+	}
+
+	if err := rule.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("%s.actions: %w", field, err))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (r *ACLRule) build() (acl.Rule, error) {
+	sources, err := parsePrefixes(r.Sources)
+	if err != nil {
+		return acl.Rule{}, err
+	}
+
+	return acl.Rule{
+		Allow:      r.Effect == aclAllow,
+		Principals: r.Principals,
+		Sources:    sources,
+		Actions:    r.actions(),
+		Tubes:      r.Tubes,
+	}, nil
+}
+
+func (r *ACLRule) actions() []acl.Action {
+	actions := make([]acl.Action, 0, len(r.Actions))
+
+	for _, a := range r.Actions {
+		actions = append(actions, acl.Action(a))
+	}
+
+	return actions
+}
@@ -0,0 +1,55 @@
+package bridge
+
+import (
+	"errors"
+	"fmt"
+	"net"
+)
+
+// AdminConfig serves the admin API over HTTP on Address. If Token is set, requests must present
+// it as a bearer token, which is required unless Address is a loopback address. Debug enables
+// pprof and runtime diagnostics, optionally starting mutex and block profiling at the given rates.
+type AdminConfig struct {
+	Address              string `yaml:"address"`
+	Token                string `yaml:"token"`
+	Debug                bool   `yaml:"debug"`
+	MutexProfileFraction int    `yaml:"mutex-profile-fraction"`
+	BlockProfileRate     int    `yaml:"block-profile-rate"`
+}
+
+func (c *AdminConfig) validate(field string) error {
+	var errs []error
+
+	if c.Address == "" {
+		errs = append(errs, fmt.Errorf("%s.address: must not be empty", field))
+	} else if host, _, err := net.SplitHostPort(c.Address); err != nil {
+		errs = append(errs, fmt.Errorf("%s.address: %w", field, err))
+	} else if c.Token == "" && !isLoopback(host) {
+		errs = append(errs, fmt.Errorf("%s.token: required unless the address is a loopback address", field))
+	}
+
+	if c.MutexProfileFraction < 0 {
+		errs = append(errs, fmt.Errorf("%s.mutex-profile-fraction: must not be negative", field))
+	} else if c.MutexProfileFraction > 0 && !c.Debug {
+		errs = append(errs, fmt.Errorf("%s.mutex-profile-fraction: requires debug to be enabled", field))
+	}
+
+	if c.BlockProfileRate < 0 {
+		errs = append(errs, fmt.Errorf("%s.block-profile-rate: must not be negative", field))
+	} else if c.BlockProfileRate > 0 && !c.Debug {
+		errs = append(errs, fmt.Errorf("%s.block-profile-rate: requires debug to be enabled", field))
+	}
+
+	return errors.Join(errs...)
+}
+
+// isLoopback reports whether host only accepts connections from the local machine.
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+
+	return ip != nil && ip.IsLoopback()
+}
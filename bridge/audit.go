@@ -0,0 +1,191 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/csnewman/beanbridge/audit"
+	"github.com/csnewman/beanbridge/backend"
+)
+
+const (
+	AuditFile   = "file"
+	AuditSyslog = "syslog"
+
+	defaultSyslogTag = "beanbridge"
+)
+
+// AuditConfig records job lifecycle events to a sink. Type is either file, writing JSON lines to
+// Path and rotating once the file reaches MaxSize bytes, or syslog, sending to the daemon at
+// Address over Network, or the local daemon if unset. Tubes and Actions select the events to
+// record. Body is one of none (the default), hash or full.
+type AuditConfig struct {
+	Type       string   `yaml:"type"`
+	Path       string   `yaml:"path"`
+	MaxSize    int64    `yaml:"max-size"`
+	MaxBackups int      `yaml:"max-backups"`
+	Network    string   `yaml:"network"`
+	Address    string   `yaml:"address"`
+	Tag        string   `yaml:"tag"`
+	Tubes      []string `yaml:"tubes"`
+	Actions    []string `yaml:"actions"`
+	Body       string   `yaml:"body"`
+}
+
+func (c *AuditConfig) validate(field string) error {
+	var errs []error
+
+	switch c.Type {
+	case AuditFile:
+		if c.Path == "" {
+			errs = append(errs, fmt.Errorf("%s.path: must not be empty", field))
+		}
+
+		if c.MaxSize < 0 {
+			errs = append(errs, fmt.Errorf("%s.max-size: must not be negative", field))
+		}
+
+		if c.MaxBackups < 0 {
+			errs = append(errs, fmt.Errorf("%s.max-backups: must not be negative", field))
+		}
+	case AuditSyslog:
+		if (c.Network == "") != (c.Address == "") {
+			errs = append(errs, fmt.Errorf("%s.address: network and address must be set together", field))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("%s.type: must be one of %s, %s", field, AuditFile, AuditSyslog))
+	}
+
+	for _, a := range c.Actions {
+		if !slices.Contains(audit.Actions, audit.Action(a)) {
+			errs = append(errs, fmt.Errorf("%s.actions: unknown action %q", field, a))
+		}
+	}
+
+	switch audit.BodyMode(c.Body) {
+	case "", audit.BodyNone, audit.BodyHash, audit.BodyFull:
+	default:
+		errs = append(errs, fmt.Errorf(
+			"%s.body: must be one of %s, %s, %s",
+			field,
+			audit.BodyNone,
+			audit.BodyHash,
+			audit.BodyFull,
+		))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (c *AuditConfig) build() (audit.Route, error) {
+	var sink audit.Sink
+
+	switch c.Type {
+	case AuditFile:
+		f, err := audit.OpenFile(c.Path, c.MaxSize, c.MaxBackups)
+		if err != nil {
+			return audit.Route{}, err
+		}
+
+		sink = f
+	case AuditSyslog:
+		tag := c.Tag
+		if tag == "" {
+			tag = defaultSyslogTag
+		}
+
+		s, err := audit.DialSyslog(c.Network, c.Address, tag)
+		if err != nil {
+			return audit.Route{}, err
+		}
+
+		sink = s
+	default:
+		return audit.Route{}, fmt.Errorf("unknown audit sink type %q", c.Type)
+	}
+
+	actions := make([]audit.Action, 0, len(c.Actions))
+
+	for _, a := range c.Actions {
+		actions = append(actions, audit.Action(a))
+	}
+
+	body := audit.BodyMode(c.Body)
+	if body == "" {
+		body = audit.BodyNone
+	}
+
+	return audit.Route{
+		Sink:    sink,
+		Tubes:   c.Tubes,
+		Actions: actions,
+		Body:    body,
+	}, nil
+}
+
+// auditEvent fills in the identity of the client, which may be called from outside the connection
+// goroutine.
+func (c *Conn) auditEvent(e *audit.Event) {
+	e.ClientID = c.conn.ID()
+	e.Remote = c.conn.Addr().String()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.principal != nil {
+		e.Principal = c.principal.Name
+	}
+}
+
+// audit records an event caused by this client.
+func (c *Conn) audit(e audit.Event, body []byte) {
+	if c.server.audit == nil {
+		return
+	}
+
+	c.auditEvent(&e)
+
+	c.server.audit.Record(e, body)
+}
+
+// auditTube looks up the tube of a job ahead of an operation that may remove it, if auditing.
+func (c *Conn) auditTube(ctx context.Context, id uint64) string {
+	if c.server.audit == nil {
+		return ""
+	}
+
+	job, err := c.backend.Peek(ctx, id)
+	if err != nil {
+		return ""
+	}
+
+	return job.Tube
+}
+
+// Audit records a job lifecycle event not caused by a beanstalk client, such as an operation made
+// through the admin API.
+func (s *Server) Audit(e audit.Event) {
+	if s.audit == nil {
+		return
+	}
+
+	s.audit.Record(e, nil)
+}
+
+// auditTimeout records a reservation expiring, attributed to the client that held it.
+func (s *Server) auditTimeout(job *backend.Job, holder *Conn) {
+	e := audit.Event{
+		Action:   audit.ActionTimeout,
+		JobID:    job.ID,
+		Tube:     job.Tube,
+		ClientID: job.Owner,
+	}
+
+	if holder != nil {
+		holder.auditEvent(&e)
+	}
+
+	s.audit.Record(e, nil)
+}
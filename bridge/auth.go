@@ -0,0 +1,73 @@
+package bridge
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/csnewman/beanbridge/auth"
+)
+
+// AuthConfig enables the "auth" command. Credentials are checked against the static users first,
+// followed by the htpasswd file. Unless Optional is set, clients must authenticate before issuing
+// any other command.
+type AuthConfig struct {
+	Users        []AuthUser `yaml:"users"`
+	HtpasswdFile string     `yaml:"htpasswd-file"`
+	Optional     bool       `yaml:"optional"`
+}
+
+type AuthUser struct {
+	Name  string `yaml:"name"`
+	Token string `yaml:"token"`
+}
+
+func (c *AuthConfig) validate(field string) error {
+	var errs []error
+
+	if len(c.Users) == 0 && c.HtpasswdFile == "" {
+		errs = append(errs, fmt.Errorf("%s: requires users or htpasswd-file to be set", field))
+	}
+
+	seen := make(map[string]bool)
+
+	for i, u := range c.Users {
+		if u.Name == "" {
+			errs = append(errs, fmt.Errorf("%s.users[%d].name: must not be empty", field, i))
+		} else if seen[u.Name] {
+			errs = append(errs, fmt.Errorf("%s.users[%d].name: duplicate user %q", field, i, u.Name))
+		}
+
+		if u.Token == "" {
+			errs = append(errs, fmt.Errorf("%s.users[%d].token: must not be empty", field, i))
+		}
+
+		seen[u.Name] = true
+	}
+
+	return errors.Join(errs...)
+}
+
+func (c *AuthConfig) build() (auth.Store, error) {
+	var chain auth.Chain
+
+	if len(c.Users) > 0 {
+		static := make(auth.StaticStore, len(c.Users))
+
+		for _, u := range c.Users {
+			static[u.Name] = u.Token
+		}
+
+		chain = append(chain, static)
+	}
+
+	if c.HtpasswdFile != "" {
+		store, err := auth.LoadHtpasswdStore(c.HtpasswdFile)
+		if err != nil {
+			return nil, err
+		}
+
+		chain = append(chain, store)
+	}
+
+	return chain, nil
+}
@@ -0,0 +1,633 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/csnewman/beanbridge/acl"
+	"github.com/csnewman/beanbridge/admin"
+	"github.com/csnewman/beanbridge/audit"
+	"github.com/csnewman/beanbridge/auth"
+	"github.com/csnewman/beanbridge/backend"
+	"github.com/csnewman/beanbridge/beanstalk"
+	"github.com/csnewman/beanbridge/events"
+	"github.com/csnewman/beanbridge/metrics"
+	"github.com/csnewman/beanbridge/ratelimit"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	// Register built-in backends
+	_ "github.com/csnewman/beanbridge/backend/memory"
+	_ "github.com/csnewman/beanbridge/backend/nullsink"
+)
+
+type Option func(s *Server)
+
+// WithLogger sets the logger used by the bridge. Defaults to slog.Default.
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *Server) {
+		s.logger = logger
+	}
+}
+
+// WithBeanstalkOptions passes additional options to the underlying beanstalk server.
+func WithBeanstalkOptions(opts ...beanstalk.Option) Option {
+	return func(s *Server) {
+		s.bsOpts = append(s.bsOpts, opts...)
+	}
+}
+
+// WithAddress sets the address the beanstalk server listens on.
+func WithAddress(address string) Option {
+	return WithBeanstalkOptions(beanstalk.WithAddress(address))
+}
+
+// WithAuthStore requires clients to authenticate with the "auth" command, checking credentials
+// against store. If optional is set, unauthenticated clients may still issue commands.
+func WithAuthStore(store auth.Store, optional bool) Option {
+	return func(s *Server) {
+		s.authStore = store
+		s.authOptional = optional
+	}
+}
+
+// WithACL restricts the tube operations clients may perform.
+func WithACL(policy *acl.Policy) Option {
+	return func(s *Server) {
+		s.acl = policy
+	}
+}
+
+// WithRateLimiter limits the rate of puts and reserves.
+func WithRateLimiter(limiter *ratelimit.Limiter) Option {
+	return func(s *Server) {
+		s.limiter = limiter
+	}
+}
+
+// WithInheritedListeners provides open listeners, typically from socket activation or a hot
+// restart. Listeners matching the metrics address are used for metrics, the rest are passed to
+// the beanstalk server, see beanstalk.WithInheritedListeners.
+func WithInheritedListeners(ls ...net.Listener) Option {
+	return func(s *Server) {
+		s.inherited = append(s.inherited, ls...)
+	}
+}
+
+// WithMetrics registers command, connection and backend metrics with reg.
+func WithMetrics(reg prometheus.Registerer) Option {
+	return func(s *Server) {
+		s.metricsReg = reg
+	}
+}
+
+// WithMetricsAddress serves metrics over HTTP on address, under path. The registry also includes
+// Go runtime and process metrics.
+func WithMetricsAddress(address string, path string) Option {
+	return func(s *Server) {
+		reg := newMetricsRegistry()
+
+		s.metricsReg = reg
+		s.metricsGatherer = reg
+		s.metricsAddr = address
+		s.metricsPath = path
+	}
+}
+
+// WithAdminAddress serves the admin API over HTTP on address. If token is set, requests must
+// present it as a bearer token. The admin API may share its address with the metrics endpoint.
+func WithAdminAddress(address string, token string) Option {
+	return func(s *Server) {
+		s.adminAddr = address
+		s.adminToken = token
+	}
+}
+
+// WithAdminDebug serves pprof and runtime diagnostics from the admin API, and labels connection
+// goroutines so that profiles can be attributed to clients. Mutex and block profiling start at the
+// given rates, see runtime.SetMutexProfileFraction and runtime.SetBlockProfileRate.
+func WithAdminDebug(mutexFraction int, blockRate int) Option {
+	return func(s *Server) {
+		s.adminDebug = true
+		s.mutexFraction = mutexFraction
+		s.blockRate = blockRate
+	}
+}
+
+// WithHealthAddress serves /healthz and /readyz probes over HTTP on address, which may be shared
+// with the metrics endpoint and admin API.
+func WithHealthAddress(address string) Option {
+	return func(s *Server) {
+		s.healthAddr = address
+	}
+}
+
+// WithAuditLogger records job lifecycle events to logger, which is closed along with the server.
+func WithAuditLogger(logger *audit.Logger) Option {
+	return func(s *Server) {
+		s.audit = logger
+	}
+}
+
+// WithTracerProvider sets the provider used to trace commands, bridge operations and backend
+// calls. Defaults to the global provider.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(s *Server) {
+		s.tracerProvider = tp
+	}
+}
+
+// withTracerShutdown registers a function to flush and stop a tracer provider owned by the bridge.
+func withTracerShutdown(shutdown func(ctx context.Context) error) Option {
+	return func(s *Server) {
+		s.tracerShutdown = shutdown
+	}
+}
+
+type Server struct {
+	logger       *slog.Logger
+	bsOpts       []beanstalk.Option
+	bs           *beanstalk.Server
+	backend      backend.Backend
+	authStore    auth.Store
+	authOptional bool
+	acl          *acl.Policy
+	limiter      *ratelimit.Limiter
+	audit        *audit.Logger
+	events       *events.Bus
+	draining     atomic.Bool
+	shuttingDown atomic.Bool
+	inherited    []net.Listener
+
+	connsMu sync.Mutex
+	conns   map[uint64]*Conn
+
+	metricsReg      prometheus.Registerer
+	metricsGatherer prometheus.Gatherer
+	metricsAddr     string
+	metricsPath     string
+	adminAddr       string
+	adminToken      string
+	adminDebug      bool
+	mutexFraction   int
+	blockRate       int
+	healthAddr      string
+	httpServers     []*httpServer
+
+	tracerProvider trace.TracerProvider
+	tracerShutdown func(ctx context.Context) error
+	tracer         trace.Tracer
+	jobs           backend.Backend
+}
+
+// NewServer creates a bridge serving the beanstalk protocol on top of b.
+func NewServer(b backend.Backend, opts ...Option) (*Server, error) {
+	s := &Server{
+		logger:  slog.Default(),
+		backend: b,
+		conns:   make(map[uint64]*Conn),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if s.tracerProvider == nil {
+		s.tracerProvider = otel.GetTracerProvider()
+	}
+
+	s.tracer = s.tracerProvider.Tracer(tracerName)
+	s.jobs = &tracedBackend{
+		Backend:    s.backend,
+		tracer:     s.tracer,
+		propagator: propagation.TraceContext{},
+	}
+
+	s.events = events.NewBus()
+
+	if p, ok := s.backend.(backend.EventPublisher); ok {
+		p.PublishEvents(s.events)
+	}
+
+	if n, ok := s.backend.(backend.TimeoutNotifier); ok {
+		n.NotifyTimeouts(s.handleTimeout)
+	}
+
+	bsOpts := append([]beanstalk.Option{
+		beanstalk.WithLogger(s.logger),
+		beanstalk.WithTracerProvider(s.tracerProvider),
+	}, s.bsOpts...)
+
+	if s.metricsAddr != "" {
+		h, err := s.httpServer("metrics", s.metricsAddr)
+		if err != nil {
+			return nil, err
+		}
+
+		h.mux.Handle(s.metricsPath, promhttp.HandlerFor(s.metricsGatherer, promhttp.HandlerOpts{}))
+	}
+
+	if s.adminAddr != "" {
+		h, err := s.httpServer("admin", s.adminAddr)
+		if err != nil {
+			s.closeHTTP()
+
+			return nil, err
+		}
+
+		var adminOpts []admin.Option
+
+		if s.adminDebug {
+			adminOpts = append(adminOpts, admin.WithDebug(s.mutexFraction, s.blockRate))
+			bsOpts = append(bsOpts, beanstalk.WithProfilerLabels())
+		}
+
+		h.mux.Handle("/", admin.NewHandler(s.logger.With("server", "admin"), s, s.adminToken, adminOpts...))
+	}
+
+	if s.healthAddr != "" {
+		h, err := s.httpServer("health", s.healthAddr)
+		if err != nil {
+			s.closeHTTP()
+
+			return nil, err
+		}
+
+		h.mux.HandleFunc("GET /healthz", s.handleHealth)
+		h.mux.HandleFunc("GET /readyz", s.handleReady)
+	}
+
+	if s.metricsReg != nil {
+		m, err := metrics.New(s.metricsReg)
+		if err != nil {
+			s.closeHTTP()
+
+			return nil, err
+		}
+
+		bsOpts = append(bsOpts, beanstalk.WithObserver(m))
+	}
+
+	if len(s.inherited) > 0 {
+		bsOpts = append(bsOpts, beanstalk.WithInheritedListeners(s.inherited...))
+		s.inherited = nil
+	}
+
+	bs, err := beanstalk.NewServer(s.NewHandler, bsOpts...)
+	if err != nil {
+		s.closeHTTP()
+
+		return nil, fmt.Errorf("failed to create beanstalk server: %w", err)
+	}
+
+	s.bs = bs
+
+	if err := s.registerMetrics(); err != nil {
+		s.bs.Close()
+		s.closeHTTP()
+
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// httpServer returns the HTTP server listening on address, creating it if needed.
+func (s *Server) httpServer(name string, address string) (*httpServer, error) {
+	for _, h := range s.httpServers {
+		if h.address == address {
+			return h, nil
+		}
+	}
+
+	h, err := newHTTPServer(s.logger, name, address, &s.inherited)
+	if err != nil {
+		return nil, err
+	}
+
+	s.httpServers = append(s.httpServers, h)
+
+	return h, nil
+}
+
+func (s *Server) registerMetrics() error {
+	if s.metricsReg == nil {
+		return nil
+	}
+
+	if err := s.metricsReg.Register(metrics.NewServerCollector(s.bs.Stats)); err != nil {
+		return fmt.Errorf("failed to register server metrics: %w", err)
+	}
+
+	if err := s.metricsReg.Register(metrics.NewBackendCollector(s.logger, s.backend)); err != nil {
+		return fmt.Errorf("failed to register backend metrics: %w", err)
+	}
+
+	return nil
+}
+
+// NewServerFromConfig creates a bridge using a registered backend, as described by cfg.
+func NewServerFromConfig(logger *slog.Logger, cfg *Config, opts ...Option) (*Server, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	bsOpts, err := cfg.beanstalkOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Auth != nil {
+		store, err := cfg.Auth.build()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create auth store: %w", err)
+		}
+
+		opts = append([]Option{WithAuthStore(store, cfg.Auth.Optional)}, opts...)
+	}
+
+	if cfg.ACL != nil {
+		policy, err := cfg.ACL.build()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create acl: %w", err)
+		}
+
+		opts = append([]Option{WithACL(policy)}, opts...)
+	}
+
+	if len(cfg.RateLimits) > 0 {
+		rules := make([]ratelimit.Rule, 0, len(cfg.RateLimits))
+
+		for _, r := range cfg.RateLimits {
+			rule, err := r.build()
+			if err != nil {
+				return nil, fmt.Errorf("failed to create rate limiter: %w", err)
+			}
+
+			rules = append(rules, rule)
+		}
+
+		opts = append([]Option{WithRateLimiter(ratelimit.NewLimiter(rules))}, opts...)
+	}
+
+	if cfg.Metrics != nil {
+		opts = append([]Option{WithMetricsAddress(cfg.Metrics.Address, cfg.Metrics.path())}, opts...)
+	}
+
+	if cfg.Admin != nil {
+		opts = append([]Option{WithAdminAddress(cfg.Admin.Address, cfg.Admin.Token)}, opts...)
+
+		if cfg.Admin.Debug {
+			opts = append([]Option{WithAdminDebug(cfg.Admin.MutexProfileFraction, cfg.Admin.BlockProfileRate)}, opts...)
+		}
+	}
+
+	if cfg.Health != nil {
+		opts = append([]Option{WithHealthAddress(cfg.Health.Address)}, opts...)
+	}
+
+	var auditLogger *audit.Logger
+
+	if len(cfg.Audit) > 0 {
+		routes := make([]audit.Route, 0, len(cfg.Audit))
+
+		for _, a := range cfg.Audit {
+			route, err := a.build()
+			if err != nil {
+				err = fmt.Errorf("failed to create audit log: %w", err)
+
+				for _, r := range routes {
+					err = errors.Join(err, r.Sink.Close())
+				}
+
+				return nil, err
+			}
+
+			routes = append(routes, route)
+		}
+
+		auditLogger = audit.NewLogger(logger, routes...)
+		opts = append([]Option{WithAuditLogger(auditLogger)}, opts...)
+	}
+
+	b, err := backend.New(cfg.Backend, logger.With("backend", cfg.Backend), cfg.decodeBackendConfig)
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("failed to create backend: %w", err), closeAuditLogger(auditLogger))
+	}
+
+	opts = append([]Option{WithLogger(logger), WithBeanstalkOptions(bsOpts...)}, opts...)
+
+	var tp *sdktrace.TracerProvider
+
+	if cfg.Tracing != nil {
+		tp, err = cfg.Tracing.build(context.Background())
+		if err != nil {
+			return nil, errors.Join(err, closeBackend(b), closeAuditLogger(auditLogger))
+		}
+
+		opts = append([]Option{WithTracerProvider(tp), withTracerShutdown(tp.Shutdown)}, opts...)
+	}
+
+	s, err := NewServer(b, opts...)
+	if err != nil {
+		err = errors.Join(err, closeBackend(b), closeAuditLogger(auditLogger))
+
+		if tp != nil {
+			err = errors.Join(err, tp.Shutdown(context.Background()))
+		}
+
+		return nil, err
+	}
+
+	return s, nil
+}
+
+const (
+	defaultTube        = "default"
+	tracerCloseTimeout = 5 * time.Second
+)
+
+// NewHandler creates the handler for a single beanstalk connection. It can be used as a
+// beanstalk.Factory to serve the bridge from a separately managed beanstalk.Server.
+func (s *Server) NewHandler(conn *beanstalk.Conn) beanstalk.Handler {
+	logger := s.logger.With("conn", conn.ID(), "remote", conn.Addr().String())
+
+	if state, ok := conn.TLSState(); ok && len(state.PeerCertificates) > 0 {
+		logger = logger.With("subject", state.PeerCertificates[0].Subject.String())
+	}
+
+	c := &Conn{
+		logger:   logger,
+		server:   s,
+		conn:     conn,
+		backend:  s.jobs,
+		source:   conn.RemoteIP(),
+		since:    time.Now(),
+		mainTube: s.backend.ResolveTube(defaultTube),
+		watching: []backend.Tube{
+			s.backend.ResolveTube(defaultTube),
+		},
+		reserved: make(map[uint64]uint64),
+	}
+
+	s.register(c)
+
+	return c
+}
+
+// SetDraining toggles drain mode. While draining, puts are rejected with DRAINING, so producers
+// move on to other servers, whilst consumers can continue to reserve and delete jobs.
+func (s *Server) SetDraining(draining bool) {
+	if s.draining.Swap(draining) == draining {
+		return
+	}
+
+	if draining {
+		s.logger.Info("Entered drain mode")
+	} else {
+		s.logger.Info("Left drain mode")
+	}
+}
+
+func (s *Server) Draining() bool {
+	return s.draining.Load()
+}
+
+func (s *Server) Backend() backend.Backend {
+	return s.backend
+}
+
+func (s *Server) Addr() net.Addr {
+	return s.bs.Addr()
+}
+
+// ListenerFiles returns duplicates of the beanstalk listener sockets, see
+// beanstalk.Server.ListenerFiles.
+func (s *Server) ListenerFiles() ([]*os.File, error) {
+	files, err := s.bs.ListenerFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, h := range s.httpServers {
+		f, err := h.file()
+		if err != nil {
+			for _, f := range files {
+				_ = f.Close()
+			}
+
+			return nil, err
+		}
+
+		files = append(files, f)
+	}
+
+	return files, nil
+}
+
+// HandOver leaves the socket files of unix listeners in place when the server is closed, see
+// beanstalk.Server.HandOver.
+func (s *Server) HandOver() {
+	s.bs.HandOver()
+
+	for _, h := range s.httpServers {
+		beanstalk.HandOver(h.l)
+	}
+}
+
+func (s *Server) Serve() error {
+	for _, h := range s.httpServers {
+		go h.serve()
+	}
+
+	return s.bs.Serve()
+}
+
+// Shutdown gracefully stops the beanstalk server, see beanstalk.Server.Shutdown, and then closes
+// the backend if it implements io.Closer. Readiness probes fail from the start of the shutdown.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.shuttingDown.Store(true)
+
+	err := s.bs.Shutdown(ctx)
+
+	for _, h := range s.httpServers {
+		err = errors.Join(err, h.shutdown(ctx))
+	}
+
+	return errors.Join(err, s.closeBackend(), s.closeAudit(), s.shutdownTracer(ctx))
+}
+
+// Close immediately stops the beanstalk server and closes the backend if it implements io.Closer.
+func (s *Server) Close() error {
+	s.bs.Close()
+	s.closeHTTP()
+
+	// Spans are still flushed, but without waiting on an unresponsive exporter
+	ctx, cancel := context.WithTimeout(context.Background(), tracerCloseTimeout)
+	defer cancel()
+
+	return errors.Join(s.closeBackend(), s.closeAudit(), s.shutdownTracer(ctx))
+}
+
+func (s *Server) closeAudit() error {
+	return closeAuditLogger(s.audit)
+}
+
+func closeAuditLogger(l *audit.Logger) error {
+	if l == nil {
+		return nil
+	}
+
+	if err := l.Close(); err != nil {
+		return fmt.Errorf("failed to close audit log: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Server) shutdownTracer(ctx context.Context) error {
+	if s.tracerShutdown == nil {
+		return nil
+	}
+
+	if err := s.tracerShutdown(ctx); err != nil {
+		return fmt.Errorf("failed to shutdown tracer: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Server) closeHTTP() {
+	for _, h := range s.httpServers {
+		h.close()
+	}
+}
+
+func (s *Server) closeBackend() error {
+	return closeBackend(s.backend)
+}
+
+func closeBackend(b backend.Backend) error {
+	closer, ok := b.(io.Closer)
+	if !ok {
+		return nil
+	}
+
+	if err := closer.Close(); err != nil {
+		return fmt.Errorf("failed to close backend: %w", err)
+	}
+
+	return nil
+}
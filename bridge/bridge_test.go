@@ -0,0 +1,800 @@
+package bridge_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	bc "github.com/beanstalkd/go-beanstalk"
+	"github.com/csnewman/beanbridge/acl"
+	"github.com/csnewman/beanbridge/audit"
+	"github.com/csnewman/beanbridge/auth"
+	"github.com/csnewman/beanbridge/backend"
+	"github.com/csnewman/beanbridge/backend/memory"
+	"github.com/csnewman/beanbridge/bridge"
+	"github.com/csnewman/beanbridge/ratelimit"
+	"github.com/neilotoole/slogt"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gopkg.in/yaml.v3"
+)
+
+func TestEmbedded(t *testing.T) {
+	t.Parallel()
+
+	s := startServer(t)
+
+	c, err := bc.Dial(s.Addr().Network(), s.Addr().String())
+	require.NoError(t, err, "Client should connect")
+
+	defer c.Close()
+
+	id, err := c.Put([]byte("hello"), 1, 0, 120*time.Second)
+	require.NoError(t, err, "Put should not error")
+
+	rid, body, err := c.Reserve(time.Second)
+	require.NoError(t, err, "Reserve should not error")
+	require.Equal(t, id, rid, "Reserve should return the put job")
+	require.Equal(t, []byte("hello"), body, "Reserve should return the put body")
+}
+
+func TestReleaseOnDisconnect(t *testing.T) {
+	t.Parallel()
+
+	s := startServer(t)
+
+	c1, err := bc.Dial(s.Addr().Network(), s.Addr().String())
+	require.NoError(t, err, "Client should connect")
+
+	id, err := c1.Put([]byte("hello"), 1, 0, 120*time.Second)
+	require.NoError(t, err, "Put should not error")
+
+	rid, _, err := c1.Reserve(time.Second)
+	require.NoError(t, err, "Reserve should not error")
+	require.Equal(t, id, rid, "Reserve should return the put job")
+
+	require.NoError(t, c1.Close(), "Close should not error")
+
+	c2, err := bc.Dial(s.Addr().Network(), s.Addr().String())
+	require.NoError(t, err, "Client should connect")
+
+	defer c2.Close()
+
+	rid, _, err = c2.Reserve(5 * time.Second)
+	require.NoError(t, err, "Reserve should not error")
+	require.Equal(t, id, rid, "Job should be released when the first client disconnects")
+}
+
+func TestExpiredReservation(t *testing.T) {
+	t.Parallel()
+
+	s := startServer(t)
+
+	first, err := bc.Dial(s.Addr().Network(), s.Addr().String())
+	require.NoError(t, err, "Client should connect")
+
+	defer first.Close()
+
+	id, err := first.Put([]byte("hello"), 1, 0, time.Second)
+	require.NoError(t, err, "Put should not error")
+
+	_, _, err = first.Reserve(0)
+	require.NoError(t, err, "Reserve should not error")
+
+	second := dialRaw(t, s.Addr())
+
+	require.Equal(t, "RESERVED 1 5\r\nhello\r\n", second("reserve-with-timeout 5"))
+
+	require.Eventually(t, func() bool {
+		clients := s.Clients()
+
+		return len(clients) == 2 && len(clients[0].Reserved) == 0 && len(clients[1].Reserved) == 1
+	}, 5*time.Second, 10*time.Millisecond, "Expired reservation should be dropped")
+
+	require.ErrorIs(t, first.Touch(id), bc.ErrNotFound, "Touch by the previous holder should fail")
+	require.ErrorIs(t, first.Release(id, 2, 0), bc.ErrNotFound, "Release by the previous holder should fail")
+	require.ErrorIs(t, first.Bury(id, 2), bc.ErrNotFound, "Bury by the previous holder should fail")
+	require.ErrorIs(t, first.Delete(id), bc.ErrNotFound, "Delete by the previous holder should fail")
+	require.NoError(t, first.Close(), "Close should not error")
+
+	require.Eventually(t, func() bool {
+		return len(s.Clients()) == 1
+	}, 5*time.Second, 10*time.Millisecond, "Previous holder should disconnect")
+
+	require.Equal(t, "TOUCHED\r\n", second("touch 1"))
+	require.Equal(t, "BURIED\r\n", second("bury 1 1"))
+}
+
+func TestDraining(t *testing.T) {
+	t.Parallel()
+
+	s := startServer(t)
+
+	send := dialRaw(t, s.Addr())
+
+	require.Equal(t, "INSERTED 1\r\n", send("put 1 0 60 5\r\nhello"))
+
+	s.SetDraining(true)
+
+	require.Equal(t, "DRAINING\r\n", send("put 1 0 60 5\r\nworld"))
+	require.Equal(t, "RESERVED 1 5\r\nhello\r\n", send("reserve-with-timeout 0"))
+	require.Equal(t, "DELETED\r\n", send("delete 1"))
+	require.Equal(t, "TIMED_OUT\r\n", send("reserve-with-timeout 0"), "Rejected put should not be stored")
+
+	s.SetDraining(false)
+
+	require.Equal(t, "INSERTED 2\r\n", send("put 1 0 60 5\r\nworld"))
+}
+
+func TestClients(t *testing.T) {
+	t.Parallel()
+
+	open := dialRaw(t, startServer(t).Addr())
+	require.Equal(t, "PERMISSION_DENIED\r\n", open("list-clients"), "Admin should be denied without an acl")
+	require.Equal(t, "PERMISSION_DENIED\r\n", open("disconnect-client 1"), "Admin should be denied without an acl")
+
+	s := startServer(
+		t,
+		bridge.WithACL(&acl.Policy{
+			Rules: []acl.Rule{
+				{
+					Allow:   true,
+					Actions: []acl.Action{acl.ActionAdmin},
+					Sources: []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")},
+				},
+			},
+			DefaultAllow: true,
+		}),
+	)
+
+	worker, err := bc.Dial(s.Addr().Network(), s.Addr().String())
+	require.NoError(t, err, "Client should connect")
+
+	defer worker.Close()
+
+	id, err := worker.Put([]byte("hello"), 1, 0, 120*time.Second)
+	require.NoError(t, err, "Put should not error")
+
+	_, _, err = worker.Reserve(time.Second)
+	require.NoError(t, err, "Reserve should not error")
+
+	blocked := make(chan error, 1)
+
+	go func() {
+		_, _, err := worker.Reserve(10 * time.Second)
+		blocked <- err
+	}()
+
+	require.Eventually(t, func() bool {
+		clients := s.Clients()
+
+		return len(clients) == 1 && clients[0].Command == "reserve-with-timeout"
+	}, 5*time.Second, 10*time.Millisecond, "Blocked reserve should be reported")
+
+	info := s.Clients()[0]
+	require.Equal(t, []uint64{id}, info.Reserved)
+	require.Equal(t, []string{"default"}, info.Watching)
+	require.NotZero(t, info.BytesIn)
+
+	send := dialRaw(t, s.Addr())
+
+	list := send("list-clients")
+	require.True(t, strings.HasPrefix(list, "OK "), "List should succeed")
+	require.Contains(t, list, "- id: "+strconv.FormatUint(info.ID, 10)+"\n")
+	require.Contains(t, list, "  command: reserve-with-timeout\n")
+
+	require.Equal(t, "DISCONNECTED\r\n", send("disconnect-client "+strconv.FormatUint(info.ID, 10)))
+	require.Error(t, <-blocked, "Blocked reserve should fail once disconnected")
+	require.Equal(t, "RESERVED "+strconv.FormatUint(id, 10)+" 5\r\nhello\r\n", send("reserve-with-timeout 5"))
+	require.Equal(t, "NOT_FOUND\r\n", send("disconnect-client "+strconv.FormatUint(info.ID, 10)))
+}
+
+func TestEvents(t *testing.T) {
+	t.Parallel()
+
+	s := startServer(t)
+
+	c, err := net.Dial(s.Addr().Network(), s.Addr().String())
+	require.NoError(t, err, "Client should connect")
+
+	defer c.Close()
+
+	_, err = c.Write([]byte("subscribe-events * buried,deleted\r\n"))
+	require.NoError(t, err, "Write should not error")
+
+	r := bufio.NewReader(c)
+
+	line, err := r.ReadString('\n')
+	require.NoError(t, err, "Read should not error")
+	require.Equal(t, "SUBSCRIBED\r\n", line)
+
+	producer, err := bc.Dial(s.Addr().Network(), s.Addr().String())
+	require.NoError(t, err, "Client should connect")
+
+	defer producer.Close()
+
+	id, err := producer.Put([]byte("hello"), 1, 0, 120*time.Second)
+	require.NoError(t, err, "Put should not error")
+
+	_, _, err = producer.Reserve(time.Second)
+	require.NoError(t, err, "Reserve should not error")
+	require.NoError(t, producer.Bury(id, 1), "Bury should not error")
+	require.NoError(t, producer.Delete(id), "Delete should not error")
+
+	for _, typ := range []string{"buried", "deleted"} {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err, "Read should not error")
+		require.True(t, strings.HasPrefix(line, "EVENT "), "Event should be streamed")
+
+		size, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "EVENT ")))
+		require.NoError(t, err, "Event size should be valid")
+
+		body := make([]byte, size+2)
+
+		_, err = io.ReadFull(r, body)
+		require.NoError(t, err, "Event should be readable")
+
+		var e map[string]any
+		require.NoError(t, json.Unmarshal(body[:size], &e))
+		require.Equal(t, typ, e["type"])
+		require.InDelta(t, id, e["job_id"], 0)
+	}
+}
+
+type unhealthyBackend struct {
+	backend.Backend
+}
+
+func (b *unhealthyBackend) Health(_ context.Context) error {
+	return errors.New("connection refused")
+}
+
+func TestReady(t *testing.T) {
+	t.Parallel()
+
+	s := startServer(t)
+	ctx := context.Background()
+
+	require.NoError(t, s.Ready(ctx), "Server should be ready")
+
+	s.SetDraining(true)
+	require.ErrorIs(t, s.Ready(ctx), bridge.ErrNotReadyDraining)
+
+	s.SetDraining(false)
+	require.NoError(t, s.Shutdown(ctx), "Shutdown should not error")
+	require.ErrorIs(t, s.Ready(ctx), bridge.ErrNotReadyShuttingDown)
+
+	s = startBackendServer(t, &unhealthyBackend{memory.NewBackend(slogt.New(t), nil)})
+
+	require.ErrorContains(t, s.Ready(ctx), "connection refused")
+}
+
+func TestAudit(t *testing.T) {
+	t.Parallel()
+
+	logger := slogt.New(t)
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	sink, err := audit.OpenFile(path, 0, 0)
+	require.NoError(t, err, "Audit log should open")
+
+	s := startServer(
+		t,
+		bridge.WithAuditLogger(audit.NewLogger(logger, audit.Route{Sink: sink, Body: audit.BodyHash})),
+	)
+
+	send := dialRaw(t, s.Addr())
+
+	require.Equal(t, "INSERTED 1\r\n", send("put 1 0 1 5\r\nhello"))
+	require.Equal(t, "RESERVED 1 5\r\nhello\r\n", send("reserve-with-timeout 0"))
+	require.Equal(t, "RESERVED 1 5\r\nhello\r\n", send("reserve-with-timeout 2"))
+	require.Equal(t, "DELETED\r\n", send("delete 1"))
+
+	require.NoError(t, s.Close(), "Close should not error")
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err, "Audit log should be readable")
+
+	var actions []audit.Action
+
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var e audit.Event
+
+		require.NoError(t, json.Unmarshal([]byte(line), &e), "Audit event should be valid JSON")
+		require.Equal(t, uint64(1), e.JobID)
+		require.Equal(t, "default", e.Tube)
+		require.NotZero(t, e.ClientID)
+
+		actions = append(actions, e.Action)
+	}
+
+	require.Equal(t, []audit.Action{
+		audit.ActionPut,
+		audit.ActionReserve,
+		audit.ActionTimeout,
+		audit.ActionReserve,
+		audit.ActionDelete,
+	}, actions)
+}
+
+func TestAuth(t *testing.T) {
+	t.Parallel()
+
+	s := startServer(
+		t,
+		bridge.WithAuthStore(auth.StaticStore{"alice": "secret"}, false),
+	)
+
+	send := dialRaw(t, s.Addr())
+
+	require.Equal(t, "AUTH_REQUIRED\r\n", send("put 1 0 10 5\r\nhello"))
+	require.Equal(t, "AUTH_REQUIRED\r\n", send("use other"))
+	require.Equal(t, "AUTH_FAILED\r\n", send("auth alice wrong"))
+	require.Equal(t, "AUTH_FAILED\r\n", send("auth bob secret"))
+	require.Equal(t, "AUTHENTICATED\r\n", send("auth alice secret"))
+	require.Equal(t, "USING other\r\n", send("use other"))
+}
+
+// memorySink collects audit events.
+type memorySink struct {
+	mu     sync.Mutex
+	events []audit.Event
+}
+
+func (s *memorySink) Write(e *audit.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.events = append(s.events, *e)
+
+	return nil
+}
+
+func (s *memorySink) Close() error {
+	return nil
+}
+
+func TestACL(t *testing.T) {
+	t.Parallel()
+
+	sink := &memorySink{}
+
+	s := startServer(
+		t,
+		bridge.WithAuditLogger(audit.NewLogger(slogt.New(t), audit.Route{
+			Sink:    sink,
+			Actions: []audit.Action{audit.ActionDenied},
+		})),
+		bridge.WithAuthStore(auth.StaticStore{"alice": "secret"}, true),
+		bridge.WithACL(&acl.Policy{
+			Rules: []acl.Rule{
+				{
+					Allow:      true,
+					Principals: []string{"alice"},
+					Actions:    []acl.Action{acl.ActionUse, acl.ActionPut, acl.ActionKick},
+					Tubes:      []string{"emails"},
+				},
+				{
+					Allow:      true,
+					Principals: []string{"alice"},
+					Actions:    []acl.Action{acl.ActionWatch, acl.ActionReserve, acl.ActionDelete},
+					Tubes:      []string{"emails-*"},
+				},
+			},
+		}),
+	)
+
+	send := dialRaw(t, s.Addr())
+
+	require.Equal(t, "PERMISSION_DENIED\r\n", send("use emails"))
+	require.Equal(t, "AUTHENTICATED\r\n", send("auth alice secret"))
+	require.Equal(t, "USING emails\r\n", send("use emails"))
+	require.Equal(t, "INSERTED 1\r\n", send("put 1 0 10 5\r\nhello"))
+	require.Equal(t, "PERMISSION_DENIED\r\n", send("reserve-with-timeout 0"))
+	require.Equal(t, "PERMISSION_DENIED\r\n", send("delete 1"))
+	require.Equal(t, "PERMISSION_DENIED\r\n", send("pause-tube emails 10"))
+	require.Equal(t, "KICKED 0\r\n", send("kick 10"))
+	require.Equal(t, "PERMISSION_DENIED\r\n", send("use other"))
+	require.Equal(t, "WATCHING 2\r\n", send("watch emails-replies"))
+	require.Equal(t, "TIMED_OUT\r\n", send("reserve-with-timeout 0"))
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+
+	type denial struct {
+		principal string
+		action    acl.Action
+		tube      string
+	}
+
+	var denials []denial
+
+	for _, e := range sink.events {
+		require.Equal(t, audit.ActionDenied, e.Action)
+		require.NotEmpty(t, e.Remote, "Denials should record the client address")
+
+		denials = append(denials, denial{e.Principal, e.Denied, e.Tube})
+	}
+
+	require.Equal(t, []denial{
+		{"", acl.ActionUse, "emails"},
+		{"alice", acl.ActionReserve, ""},
+		{"alice", acl.ActionDelete, "emails"},
+		{"alice", acl.ActionPause, "emails"},
+		{"alice", acl.ActionUse, "other"},
+	}, denials)
+}
+
+func TestRateLimit(t *testing.T) {
+	t.Parallel()
+
+	s := startServer(
+		t,
+		bridge.WithRateLimiter(ratelimit.NewLimiter([]ratelimit.Rule{
+			{
+				Actions: []acl.Action{acl.ActionPut},
+				Tubes:   []string{"default"},
+				Scope:   ratelimit.ScopeTube,
+				Rate:    0.001,
+				Burst:   1,
+				Mode:    ratelimit.ModeBury,
+			},
+			{
+				Actions: []acl.Action{acl.ActionReserve},
+				Scope:   ratelimit.ScopeClient,
+				Rate:    0.001,
+				Burst:   1,
+				Mode:    ratelimit.ModeReject,
+			},
+		})),
+	)
+
+	send := dialRaw(t, s.Addr())
+
+	require.Equal(t, "INSERTED 1\r\n", send("put 1 0 10 5\r\nhello"))
+	require.Equal(t, "BURIED 2\r\n", send("put 1 0 10 5\r\nhello"))
+	require.Equal(t, "RESERVED 1 5\r\nhello\r\n", send("reserve-with-timeout 0"))
+	require.Equal(t, "THROTTLED\r\n", send("reserve-with-timeout 0"))
+}
+
+func TestRateLimitTube(t *testing.T) {
+	t.Parallel()
+
+	s := startServer(
+		t,
+		bridge.WithRateLimiter(ratelimit.NewLimiter([]ratelimit.Rule{
+			{
+				Actions: []acl.Action{acl.ActionReserve},
+				Tubes:   []string{"limited"},
+				Scope:   ratelimit.ScopeTube,
+				Rate:    0.001,
+				Burst:   1,
+				Mode:    ratelimit.ModeReject,
+			},
+		})),
+	)
+
+	send := dialRaw(t, s.Addr())
+
+	require.Equal(t, "USING limited\r\n", send("use limited"))
+	require.Equal(t, "INSERTED 1\r\n", send("put 1 0 10 5\r\nfirst"))
+	require.Equal(t, "INSERTED 2\r\n", send("put 1 0 10 6\r\nsecond"))
+	require.Equal(t, "USING other\r\n", send("use other"))
+	require.Equal(t, "INSERTED 3\r\n", send("put 1 0 10 5\r\nother"))
+	require.Equal(t, "WATCHING 2\r\n", send("watch limited"))
+	require.Equal(t, "WATCHING 3\r\n", send("watch other"))
+
+	require.Equal(t, "RESERVED 1 5\r\nfirst\r\n", send("reserve-with-timeout 0"))
+	require.Equal(t, "RESERVED 3 5\r\nother\r\n", send("reserve-with-timeout 0"), "Throttled tube should be skipped")
+	require.Equal(t, "TIMED_OUT\r\n", send("reserve-with-timeout 0"))
+	require.Equal(t, "WATCHING 2\r\n", send("ignore other"))
+	require.Equal(t, "WATCHING 1\r\n", send("ignore default"))
+	require.Equal(t, "THROTTLED\r\n", send("reserve-with-timeout 0"))
+	require.Equal(t, "RESERVED 2 6\r\nsecond\r\n", send("reserve-job 2"), "Throttled job should be left ready")
+}
+
+func TestMetrics(t *testing.T) {
+	t.Parallel()
+
+	reg := prometheus.NewRegistry()
+
+	s := startServer(
+		t,
+		bridge.WithMetrics(reg),
+	)
+
+	send := dialRaw(t, s.Addr())
+
+	require.Equal(t, "INSERTED 1\r\n", send("put 1 0 10 5\r\nhello"))
+	require.Equal(t, "NOT_FOUND\r\n", send("delete 2"))
+
+	expected := `
+# HELP beanbridge_commands_total Number of beanstalk commands processed, by command and response status.
+# TYPE beanbridge_commands_total counter
+beanbridge_commands_total{command="delete",status="NOT_FOUND"} 1
+beanbridge_commands_total{command="put",status="INSERTED"} 1
+# HELP beanbridge_connections_active Number of open beanstalk connections.
+# TYPE beanbridge_connections_active gauge
+beanbridge_connections_active 1
+# HELP beanbridge_tube_jobs Number of jobs in the tube, by state.
+# TYPE beanbridge_tube_jobs gauge
+beanbridge_tube_jobs{state="buried",tube="default"} 0
+beanbridge_tube_jobs{state="delayed",tube="default"} 0
+beanbridge_tube_jobs{state="ready",tube="default"} 1
+beanbridge_tube_jobs{state="reserved",tube="default"} 0
+`
+
+	err := testutil.GatherAndCompare(
+		reg,
+		strings.NewReader(expected),
+		"beanbridge_commands_total",
+		"beanbridge_connections_active",
+		"beanbridge_tube_jobs",
+	)
+	require.NoError(t, err, "Metrics should match")
+}
+
+func TestTracing(t *testing.T) {
+	t.Parallel()
+
+	recorder := tracetest.NewSpanRecorder()
+
+	s := startServer(
+		t,
+		bridge.WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))),
+	)
+
+	producer := dialRaw(t, s.Addr())
+	consumer := dialRaw(t, s.Addr())
+
+	require.Equal(t, "INSERTED 1\r\n", producer("put 1 0 10 5\r\nhello"))
+	require.Equal(t, "RESERVED 1 5\r\nhello\r\n", consumer("reserve-with-timeout 0"))
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+
+	// Protocol spans end after the response is written, so may not have ended yet
+	require.Eventually(t, func() bool {
+		for _, span := range recorder.Ended() {
+			spans[span.Name()] = span
+		}
+
+		return spans["beanstalk put"] != nil && spans["beanstalk reserve-with-timeout"] != nil
+	}, time.Second, 10*time.Millisecond, "Commands should be traced")
+
+	require.Contains(t, spans, "bridge.Put")
+	require.Contains(t, spans, "bridge.Reserve")
+
+	put := spans["backend.Put"]
+	require.NotNil(t, put, "Backend put should be traced")
+	require.Equal(t, spans["bridge.Put"].SpanContext().SpanID(), put.Parent().SpanID())
+
+	reserve := spans["backend.Reserve"]
+	require.NotNil(t, reserve, "Backend reserve should be traced")
+	require.Len(t, reserve.Links(), 1, "Reserve should link to the producer")
+	require.Equal(t, put.SpanContext().TraceID(), reserve.Links()[0].SpanContext.TraceID())
+}
+
+func TestBackendConfig(t *testing.T) {
+	t.Parallel()
+
+	cfg := &bridge.Config{
+		Address: "127.0.0.1:0",
+		Backend: "memory",
+	}
+
+	require.NoError(t, yaml.Unmarshal([]byte("poll-intervall: 1s"), &cfg.BackendConfig))
+
+	require.ErrorContains(t, cfg.Validate(), "backend-config: ", "Validation should decode the backend config")
+
+	_, err := bridge.NewServerFromConfig(slogt.New(t), cfg)
+	require.ErrorContains(t, err, "field poll-intervall not found", "Unknown fields should be rejected")
+
+	require.NoError(t, yaml.Unmarshal([]byte("poll-interval: soon"), &cfg.BackendConfig))
+	require.ErrorContains(t, cfg.Validate(), "backend-config: ", "Invalid values should be rejected")
+}
+
+func TestListenerConfig(t *testing.T) {
+	t.Parallel()
+
+	cfg := &bridge.Config{
+		Address: "127.0.0.1:0",
+		Backend: "memory",
+		Listeners: []bridge.ListenerConfig{{
+			Type:          bridge.ListenerTCP,
+			Address:       "127.0.0.1:0",
+			ProxyProtocol: true,
+		}},
+	}
+
+	require.ErrorContains(
+		t,
+		cfg.Validate(),
+		"listeners[0].trusted-proxies: required when proxy-protocol is enabled",
+		"PROXY headers should not be accepted from any peer",
+	)
+
+	cfg.Listeners[0].TrustedProxies = []string{"10.0.0.0/8"}
+
+	require.NoError(t, cfg.Validate())
+}
+
+func TestLogConfig(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		sampling string
+		err      string
+	}{
+		{sampling: "{}"},
+		{sampling: "{thereafter: 0}"},
+		{sampling: "{first: 0}"},
+		{sampling: "{first: 0, thereafter: 0}", err: "log.sampling: first and thereafter must not both be zero"},
+		{sampling: "{first: -1}", err: "log.sampling.first: must not be negative"},
+	}
+
+	for _, tt := range tests {
+		cfg := &bridge.Config{
+			Address: "127.0.0.1:0",
+			Backend: "memory",
+			Log:     &bridge.LogConfig{},
+		}
+
+		require.NoError(t, yaml.Unmarshal([]byte("sampling: "+tt.sampling), cfg.Log))
+
+		if tt.err != "" {
+			require.ErrorContains(t, cfg.Validate(), tt.err, "Sampling %s should be rejected", tt.sampling)
+		} else {
+			require.NoError(t, cfg.Validate(), "Sampling %s should be accepted", tt.sampling)
+		}
+	}
+}
+
+func TestAdminConfig(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		address string
+		token   string
+		err     string
+	}{
+		{address: "127.0.0.1:9091"},
+		{address: "[::1]:9091"},
+		{address: "localhost:9091"},
+		{address: ":9091", err: "admin.token: required unless the address is a loopback address"},
+		{address: "0.0.0.0:9091", err: "admin.token: required unless the address is a loopback address"},
+		{address: "10.0.0.1:9091", err: "admin.token: required unless the address is a loopback address"},
+		{address: ":9091", token: "s3cret"},
+	}
+
+	for _, tt := range tests {
+		cfg := &bridge.Config{
+			Address: "127.0.0.1:0",
+			Backend: "memory",
+			Admin: &bridge.AdminConfig{
+				Address: tt.address,
+				Token:   tt.token,
+				Debug:   true,
+			},
+		}
+
+		if tt.err != "" {
+			require.ErrorContains(t, cfg.Validate(), tt.err, "Address %s should require a token", tt.address)
+		} else {
+			require.NoError(t, cfg.Validate(), "Address %s should be accepted", tt.address)
+		}
+	}
+}
+
+func TestMetricsConfig(t *testing.T) {
+	t.Parallel()
+
+	cfg := &bridge.Config{
+		Address: "127.0.0.1:0",
+		Backend: "memory",
+		Metrics: &bridge.MetricsConfig{Address: "127.0.0.1:9091", Path: "/"},
+		Admin:   &bridge.AdminConfig{Address: "127.0.0.1:9091"},
+	}
+
+	require.ErrorContains(
+		t,
+		cfg.Validate(),
+		"metrics.path: must not be / when sharing an address with admin",
+		"Metrics and admin should not both be served from the root",
+	)
+
+	cfg.Metrics.Path = "/metrics"
+
+	require.NoError(t, cfg.Validate())
+
+	cfg.Metrics = &bridge.MetricsConfig{Address: "127.0.0.1:9090", Path: "/"}
+
+	require.NoError(t, cfg.Validate(), "Metrics may be served from the root of their own address")
+}
+
+// startServer serves a bridge on top of a memory backend until the test ends.
+func startServer(t *testing.T, opts ...bridge.Option) *bridge.Server {
+	t.Helper()
+
+	return startBackendServer(t, memory.NewBackend(slogt.New(t), &memory.Config{PollInterval: 10 * time.Millisecond}), opts...)
+}
+
+// startBackendServer serves a bridge on top of b until the test ends.
+func startBackendServer(t *testing.T, b backend.Backend, opts ...bridge.Option) *bridge.Server {
+	t.Helper()
+
+	logger := slogt.New(t)
+
+	s, err := bridge.NewServer(b, append([]bridge.Option{
+		bridge.WithLogger(logger),
+		bridge.WithAddress("127.0.0.1:0"),
+	}, opts...)...)
+	require.NoError(t, err, "Server should not error")
+
+	served := make(chan error, 1)
+
+	go func() {
+		served <- s.Serve()
+	}()
+
+	t.Cleanup(func() {
+		// Tests may have already stopped the server
+		_ = s.Close()
+
+		require.NoError(t, <-served, "Serve should not error")
+	})
+
+	return s
+}
+
+// dialRaw connects to the server, returning a function that sends a command and reads the
+// response, including any body.
+func dialRaw(t *testing.T, addr net.Addr) func(cmd string) string {
+	t.Helper()
+
+	c, err := net.Dial(addr.Network(), addr.String())
+	require.NoError(t, err, "Client should connect")
+
+	t.Cleanup(func() {
+		c.Close()
+	})
+
+	r := bufio.NewReader(c)
+
+	return func(cmd string) string {
+		_, err := c.Write([]byte(cmd + "\r\n"))
+		require.NoError(t, err, "Write should not error")
+
+		line, err := r.ReadString('\n')
+		require.NoError(t, err, "Read should not error")
+
+		if !strings.HasPrefix(line, "RESERVED ") && !strings.HasPrefix(line, "OK ") {
+			return line
+		}
+
+		fields := strings.Fields(line)
+
+		size, err := strconv.Atoi(fields[len(fields)-1])
+		require.NoError(t, err, "Body size should be valid")
+
+		body := make([]byte, size+2)
+
+		_, err = io.ReadFull(r, body)
+		require.NoError(t, err, "Body should be readable")
+
+		return line + string(body)
+	}
+}
@@ -0,0 +1,140 @@
+package bridge
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"maps"
+	"slices"
+
+	"github.com/csnewman/beanbridge/acl"
+	"github.com/csnewman/beanbridge/backend"
+	"github.com/csnewman/beanbridge/beanstalk"
+)
+
+func (s *Server) register(c *Conn) {
+	s.connsMu.Lock()
+	defer s.connsMu.Unlock()
+
+	s.conns[c.conn.ID()] = c
+}
+
+func (s *Server) unregister(c *Conn) {
+	s.connsMu.Lock()
+	defer s.connsMu.Unlock()
+
+	delete(s.conns, c.conn.ID())
+}
+
+// Clients returns a snapshot of the connected clients, ordered by id.
+func (s *Server) Clients() []beanstalk.ClientInfo {
+	s.connsMu.Lock()
+	conns := slices.Collect(maps.Values(s.conns))
+	s.connsMu.Unlock()
+
+	clients := make([]beanstalk.ClientInfo, 0, len(conns))
+
+	for _, c := range conns {
+		clients = append(clients, c.info())
+	}
+
+	slices.SortFunc(clients, func(a, b beanstalk.ClientInfo) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	return clients
+}
+
+// Disconnect forcibly closes the connection of a client, releasing any jobs it has reserved.
+// Returns beanstalk.ErrNotFound if there is no such client.
+func (s *Server) Disconnect(id uint64) error {
+	s.connsMu.Lock()
+	c, ok := s.conns[id]
+	s.connsMu.Unlock()
+
+	if !ok {
+		return beanstalk.ErrNotFound
+	}
+
+	c.logger.Info("Disconnecting client")
+
+	c.conn.Disconnect()
+
+	return nil
+}
+
+func (c *Conn) info() beanstalk.ClientInfo {
+	info := c.conn.Info()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.principal != nil {
+		info.Principal = c.principal.Name
+	}
+
+	info.Using = c.mainTube.Name()
+	info.Watching = make([]string, 0, len(c.watching))
+
+	for _, t := range c.watching {
+		info.Watching = append(info.Watching, t.Name())
+	}
+
+	info.Reserved = slices.Sorted(maps.Keys(c.reserved))
+
+	return info
+}
+
+func (c *Conn) setReserved(id uint64, pri uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.reserved[id] = pri
+}
+
+func (c *Conn) clearReserved(id uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.reserved, id)
+}
+
+// dropReserved forgets a job the backend no longer considers reserved by this connection, such as
+// one whose reservation expired.
+func (c *Conn) dropReserved(id uint64, err error) {
+	if errors.Is(err, beanstalk.ErrNotFound) {
+		c.clearReserved(id)
+	}
+}
+
+// handleTimeout records an expired reservation and forgets it, if the client that held it is still
+// connected.
+func (s *Server) handleTimeout(job *backend.Job) {
+	s.connsMu.Lock()
+	c := s.conns[job.Owner]
+	s.connsMu.Unlock()
+
+	if c != nil {
+		c.clearReserved(job.ID)
+	}
+
+	if s.audit != nil {
+		s.auditTimeout(job, c)
+	}
+}
+
+func (c *Conn) ListClients(_ context.Context) ([]beanstalk.ClientInfo, error) {
+	if !c.allowed(acl.ActionAdmin, "") {
+		return nil, beanstalk.ErrPermission
+	}
+
+	return c.server.Clients(), nil
+}
+
+func (c *Conn) DisconnectClient(_ context.Context, id uint64) error {
+	if !c.allowed(acl.ActionAdmin, "") {
+		return beanstalk.ErrPermission
+	}
+
+	return c.server.Disconnect(id)
+}
@@ -0,0 +1,263 @@
+package bridge
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/csnewman/beanbridge/backend"
+	"github.com/csnewman/beanbridge/beanstalk"
+	"gopkg.in/yaml.v3"
+)
+
+const DefaultShutdownTimeout = 30 * time.Second
+
+type Config struct {
+	Address             string            `yaml:"address"`
+	Backend             string            `yaml:"backend"`
+	BackendConfig       yaml.Node         `yaml:"backend-config"`
+	ShutdownTimeout     time.Duration     `yaml:"shutdown-timeout"`
+	Log                 *LogConfig        `yaml:"log"`
+	MaxJobSize          int               `yaml:"max-job-size"`
+	MaxLineLength       int               `yaml:"max-line-length"`
+	MaxConnections      int               `yaml:"max-connections"`
+	MaxConnectionsPerIP int               `yaml:"max-connections-per-ip"`
+	IdleTimeout         time.Duration     `yaml:"idle-timeout"`
+	WriteTimeout        time.Duration     `yaml:"write-timeout"`
+	TLS                 *TLSConfig        `yaml:"tls"`
+	Auth                *AuthConfig       `yaml:"auth"`
+	ACL                 *ACLConfig        `yaml:"acl"`
+	RateLimits          []RateLimitConfig `yaml:"rate-limits"`
+	Metrics             *MetricsConfig    `yaml:"metrics"`
+	Tracing             *TracingConfig    `yaml:"tracing"`
+	Admin               *AdminConfig      `yaml:"admin"`
+	Health              *HealthConfig     `yaml:"health"`
+	Audit               []AuditConfig     `yaml:"audit"`
+	// Listeners are served in addition to Address.
+	Listeners []ListenerConfig `yaml:"listeners"`
+}
+
+// decodeBackendConfig decodes the backend-config section into v, rejecting unknown fields.
+func (c *Config) decodeBackendConfig(v any) error {
+	if c.BackendConfig.IsZero() {
+		return nil
+	}
+
+	// yaml.Node.Decode does not support strict decoding, so the section is re-encoded
+	raw, err := yaml.Marshal(&c.BackendConfig)
+	if err != nil {
+		return err
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(raw))
+	dec.KnownFields(true)
+
+	return dec.Decode(v)
+}
+
+// GetShutdownTimeout returns the configured shutdown timeout, or DefaultShutdownTimeout if unset.
+func (c *Config) GetShutdownTimeout() time.Duration {
+	if c.ShutdownTimeout == 0 {
+		return DefaultShutdownTimeout
+	}
+
+	return c.ShutdownTimeout
+}
+
+// listeners returns all configured listeners, including the one described by Address and TLS.
+func (c *Config) listeners() []ListenerConfig {
+	var listeners []ListenerConfig
+
+	if c.Address != "" {
+		l := ListenerConfig{
+			Type:    ListenerTCP,
+			Address: c.Address,
+			TLS:     c.TLS,
+		}
+
+		if c.TLS != nil {
+			l.Type = ListenerTCPTLS
+		}
+
+		listeners = append(listeners, l)
+	}
+
+	return append(listeners, c.Listeners...)
+}
+
+func (c *Config) beanstalkOptions() ([]beanstalk.Option, error) {
+	var opts []beanstalk.Option
+
+	for _, l := range c.listeners() {
+		cfg, err := l.build()
+		if err != nil {
+			return nil, err
+		}
+
+		opts = append(opts, beanstalk.WithListener(cfg))
+	}
+
+	if c.MaxJobSize > 0 {
+		opts = append(opts, beanstalk.WithMaxJobSize(c.MaxJobSize))
+	}
+
+	if c.MaxLineLength > 0 {
+		opts = append(opts, beanstalk.WithMaxLineLength(c.MaxLineLength))
+	}
+
+	if c.MaxConnections > 0 {
+		opts = append(opts, beanstalk.WithMaxConnections(c.MaxConnections))
+	}
+
+	if c.MaxConnectionsPerIP > 0 {
+		opts = append(opts, beanstalk.WithMaxConnectionsPerIP(c.MaxConnectionsPerIP))
+	}
+
+	if c.IdleTimeout > 0 {
+		opts = append(opts, beanstalk.WithIdleTimeout(c.IdleTimeout))
+	}
+
+	if c.WriteTimeout > 0 {
+		opts = append(opts, beanstalk.WithWriteTimeout(c.WriteTimeout))
+	}
+
+	if c.Log != nil {
+		opts = append(opts, c.Log.beanstalkOptions()...)
+	}
+
+	return opts, nil
+}
+
+// Validate checks the configuration for errors that would prevent the server from starting.
+func (c *Config) Validate() error {
+	var errs []error
+
+	if c.Address == "" && len(c.Listeners) == 0 {
+		errs = append(errs, errors.New("address: must not be empty when no listeners are configured"))
+	} else if c.Address != "" {
+		if _, _, err := net.SplitHostPort(c.Address); err != nil {
+			errs = append(errs, fmt.Errorf("address: %w", err))
+		}
+	}
+
+	if c.TLS != nil && c.Address == "" {
+		errs = append(errs, errors.New("tls: requires address to be set"))
+	}
+
+	for i, l := range c.Listeners {
+		if err := l.validate(fmt.Sprintf("listeners[%d]", i)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if c.Backend == "" {
+		errs = append(errs, errors.New("backend: must not be empty"))
+	} else if names := backend.Names(); !slices.Contains(names, c.Backend) {
+		errs = append(errs, fmt.Errorf(
+			"backend: unknown backend %q, expected one of %s",
+			c.Backend,
+			strings.Join(names, ", "),
+		))
+	} else if err := backend.ValidateConfig(c.Backend, c.decodeBackendConfig); err != nil {
+		errs = append(errs, fmt.Errorf("backend-config: %w", err))
+	}
+
+	if c.MaxJobSize < 0 {
+		errs = append(errs, errors.New("max-job-size: must not be negative"))
+	}
+
+	if c.MaxLineLength != 0 && c.MaxLineLength < beanstalk.DefaultMaxLineLength {
+		errs = append(errs, fmt.Errorf("max-line-length: must be at least %d", beanstalk.DefaultMaxLineLength))
+	}
+
+	if c.MaxConnections < 0 {
+		errs = append(errs, errors.New("max-connections: must not be negative"))
+	}
+
+	if c.MaxConnectionsPerIP < 0 {
+		errs = append(errs, errors.New("max-connections-per-ip: must not be negative"))
+	}
+
+	if c.IdleTimeout < 0 {
+		errs = append(errs, errors.New("idle-timeout: must not be negative"))
+	}
+
+	if c.WriteTimeout < 0 {
+		errs = append(errs, errors.New("write-timeout: must not be negative"))
+	}
+
+	if c.Log != nil {
+		if err := c.Log.validate("log"); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if c.TLS != nil {
+		if err := c.TLS.validate("tls"); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if c.Auth != nil {
+		if err := c.Auth.validate("auth"); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if c.ACL != nil {
+		if err := c.ACL.validate("acl"); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for i, r := range c.RateLimits {
+		if err := r.validate(fmt.Sprintf("rate-limits[%d]", i)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if c.Metrics != nil {
+		if err := c.Metrics.validate("metrics"); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if c.Tracing != nil {
+		if err := c.Tracing.validate("tracing"); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if c.Admin != nil {
+		if err := c.Admin.validate("admin"); err != nil {
+			errs = append(errs, err)
+		}
+
+		// The admin API is served from the root of its listener
+		if c.Metrics != nil && c.Metrics.Address == c.Admin.Address && c.Metrics.path() == "/" {
+			errs = append(errs, errors.New("metrics.path: must not be / when sharing an address with admin"))
+		}
+	}
+
+	if c.Health != nil {
+		if err := c.Health.validate("health"); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for i, a := range c.Audit {
+		if err := a.validate(fmt.Sprintf("audit[%d]", i)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if c.ShutdownTimeout < 0 {
+		errs = append(errs, errors.New("shutdown-timeout: must not be negative"))
+	}
+
+	return errors.Join(errs...)
+}
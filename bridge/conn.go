@@ -0,0 +1,614 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"maps"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/csnewman/beanbridge/acl"
+	"github.com/csnewman/beanbridge/audit"
+	"github.com/csnewman/beanbridge/auth"
+	"github.com/csnewman/beanbridge/backend"
+	"github.com/csnewman/beanbridge/beanstalk"
+	"github.com/csnewman/beanbridge/ratelimit"
+)
+
+type Conn struct {
+	logger  *slog.Logger
+	server  *Server
+	conn    *beanstalk.Conn
+	backend backend.Backend
+	source  netip.Addr
+	since   time.Time
+
+	// mu guards mainTube, watching, reserved and principal against concurrent reads from the
+	// registry
+	mu       sync.Mutex
+	mainTube backend.Tube
+	watching []backend.Tube
+
+	// reserved maps the ids of jobs reserved by this connection to their priority
+	reserved map[uint64]uint64
+
+	principal *auth.Principal
+}
+
+// Principal returns the authenticated principal, or nil if the client has not authenticated.
+func (c *Conn) Principal() *auth.Principal {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.principal
+}
+
+func (c *Conn) Authenticated() bool {
+	if c.server.authStore == nil || c.server.authOptional {
+		return true
+	}
+
+	return c.Principal() != nil
+}
+
+func (c *Conn) Auth(ctx context.Context, user string, token string) error {
+	if c.server.authStore == nil {
+		c.logger.Warn("Authentication attempted but not configured", "user", user)
+
+		return beanstalk.ErrAuthFailed
+	}
+
+	principal, err := c.server.authStore.Authenticate(ctx, user, token)
+	if errors.Is(err, auth.ErrInvalidCredentials) {
+		c.logger.Warn("Authentication failed", "user", user)
+
+		return beanstalk.ErrAuthFailed
+	} else if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.principal = principal
+	c.mu.Unlock()
+
+	c.logger = c.logger.With("principal", principal.Name)
+
+	c.logger.Info("Client authenticated")
+
+	return nil
+}
+
+// withOwner marks backend requests as made by this connection, so that the backend only allows it
+// to modify jobs it has reserved.
+func (c *Conn) withOwner(ctx context.Context) context.Context {
+	return backend.WithOwner(ctx, c.conn.ID())
+}
+
+func (c *Conn) request(action acl.Action, tube string) acl.Request {
+	req := acl.Request{
+		Source: c.source,
+		Action: action,
+		Tube:   tube,
+	}
+
+	if c.principal != nil {
+		req.Principal = c.principal.Name
+	}
+
+	return req
+}
+
+// permitted checks the acl without recording denials.
+func (c *Conn) permitted(action acl.Action, tube string) bool {
+	if c.server.acl == nil {
+		// Client management must be granted explicitly
+		return action != acl.ActionAdmin
+	}
+
+	return c.server.acl.Allowed(c.request(action, tube))
+}
+
+// allowed checks the acl, logging and auditing any denied request.
+func (c *Conn) allowed(action acl.Action, tube string) bool {
+	if c.permitted(action, tube) {
+		return true
+	}
+
+	c.denied(action, tube)
+
+	return false
+}
+
+func (c *Conn) denied(action acl.Action, tube string) {
+	c.logger.Warn("Permission denied", "action", action, "tube", tube)
+
+	c.audit(audit.Event{Action: audit.ActionDenied, Denied: action, Tube: tube}, nil)
+}
+
+// checkJob checks the acl against the tube containing the job.
+func (c *Conn) checkJob(ctx context.Context, action acl.Action, id uint64) error {
+	if c.server.acl == nil {
+		return nil
+	}
+
+	job, err := c.backend.Peek(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if !c.allowed(action, job.Tube) {
+		return beanstalk.ErrPermission
+	}
+
+	return nil
+}
+
+func (c *Conn) Use(_ context.Context, tube string) (string, error) {
+	if !c.allowed(acl.ActionUse, tube) {
+		return "", beanstalk.ErrPermission
+	}
+
+	if c.mainTube.Name() == tube {
+		return tube, nil
+	}
+
+	c.mainTube.Release()
+
+	// Resolved before locking, as backends may take their own locks
+	t := c.backend.ResolveTube(tube)
+
+	c.mu.Lock()
+	c.mainTube = t
+	c.mu.Unlock()
+
+	return tube, nil
+}
+
+func (c *Conn) Put(ctx context.Context, pri uint64, delay uint64, ttr uint64, data []byte) (uint64, bool, error) {
+	ctx, span := c.startSpan(ctx, "Put", beanstalk.AttrTube.String(c.mainTube.Name()))
+	defer span.End()
+
+	id, buried, err := c.put(ctx, pri, delay, ttr, data)
+	if err != nil {
+		return 0, false, err
+	}
+
+	c.audit(audit.Event{Action: audit.ActionPut, JobID: id, Tube: c.mainTube.Name()}, data)
+
+	if buried {
+		c.audit(audit.Event{Action: audit.ActionBury, JobID: id, Tube: c.mainTube.Name()}, nil)
+	}
+
+	return id, buried, nil
+}
+
+func (c *Conn) put(ctx context.Context, pri uint64, delay uint64, ttr uint64, data []byte) (uint64, bool, error) {
+	if c.server.Draining() {
+		return 0, false, beanstalk.ErrDraining
+	}
+
+	if !c.allowed(acl.ActionPut, c.mainTube.Name()) {
+		return 0, false, beanstalk.ErrPermission
+	}
+
+	if c.server.limiter == nil {
+		return c.backend.Put(ctx, c.mainTube, pri, delay, ttr, data)
+	}
+
+	d := c.server.limiter.Take(c.request(acl.ActionPut, c.mainTube.Name()))
+	if err := c.applyLimit(ctx, acl.ActionPut, c.mainTube.Name(), d); err != nil {
+		return 0, false, err
+	}
+
+	if d.Bury {
+		ctx = backend.WithBury(ctx)
+	}
+
+	return c.backend.Put(ctx, c.mainTube, pri, delay, ttr, data)
+}
+
+// applyLimit waits out any delay required by the rate limiter, or returns ErrThrottled if the
+// request was rejected.
+func (c *Conn) applyLimit(ctx context.Context, action acl.Action, tube string, d ratelimit.Decision) error {
+	if d.Reject {
+		c.logger.Debug("Rate limit exceeded, rejecting", "action", action, "tube", tube)
+
+		return beanstalk.ErrThrottled
+	}
+
+	if d.Bury {
+		c.logger.Debug("Rate limit exceeded, burying", "action", action, "tube", tube)
+	}
+
+	if d.Delay <= 0 {
+		return nil
+	}
+
+	c.logger.Debug("Rate limit exceeded, delaying", "action", action, "tube", tube, "delay", d.Delay)
+
+	timer := time.NewTimer(d.Delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *Conn) Watch(_ context.Context, tube string) (int, error) {
+	if !c.allowed(acl.ActionWatch, tube) {
+		return 0, beanstalk.ErrPermission
+	}
+
+	for _, t := range c.watching {
+		if t.Name() == tube {
+			return len(c.watching), nil
+		}
+	}
+
+	t := c.backend.ResolveTube(tube)
+
+	c.mu.Lock()
+	c.watching = append(c.watching, t)
+	c.mu.Unlock()
+
+	return len(c.watching), nil
+}
+
+func (c *Conn) Ignore(_ context.Context, tube string) (int, error) {
+	var newWatching []backend.Tube
+
+	var ignored backend.Tube
+
+	for _, t := range c.watching {
+		if t.Name() == tube {
+			ignored = t
+
+			continue
+		}
+
+		newWatching = append(newWatching, t)
+	}
+
+	if ignored == nil {
+		return len(c.watching), nil
+	}
+
+	// Connections must always watch at least one tube
+	if len(newWatching) == 0 {
+		return 0, beanstalk.ErrNotIgnored
+	}
+
+	ignored.Release()
+
+	c.mu.Lock()
+	c.watching = newWatching
+	c.mu.Unlock()
+
+	return len(c.watching), nil
+}
+
+func (c *Conn) Reserve(ctx context.Context, timeout int64) (uint64, []byte, error) {
+	ctx, span := c.startSpan(ctx, "Reserve")
+	defer span.End()
+
+	ctx = c.withOwner(ctx)
+
+	tubes := c.watching
+
+	if c.server.acl != nil {
+		tubes = nil
+
+		// Only reserve from the watched tubes that the client may reserve from
+		for _, t := range c.watching {
+			if c.permitted(acl.ActionReserve, t.Name()) {
+				tubes = append(tubes, t)
+			}
+		}
+
+		if len(tubes) == 0 {
+			c.denied(acl.ActionReserve, "")
+
+			return 0, nil, beanstalk.ErrPermission
+		}
+	}
+
+	var (
+		job *backend.Job
+		err error
+	)
+
+	if c.server.limiter != nil {
+		d := c.server.limiter.Take(c.request(acl.ActionReserve, ""))
+		if err := c.applyLimit(ctx, acl.ActionReserve, "", d); err != nil {
+			return 0, nil, err
+		}
+
+		job, err = c.reserveLimited(ctx, tubes, timeout)
+	} else {
+		job, err = c.backend.Reserve(ctx, tubes, timeout)
+	}
+
+	if err != nil {
+		return 0, nil, err
+	}
+
+	c.setReserved(job.ID, job.Priority)
+
+	c.audit(audit.Event{Action: audit.ActionReserve, JobID: job.ID, Tube: job.Tube}, job.Data)
+
+	return job.ID, job.Data, nil
+}
+
+// reserveLimited reserves a job from the tubes whose tube limits have a token available, so that
+// throttled tubes are never reserved from. Tubes limited in delay mode are waited on until a token
+// is available, or the timeout elapses.
+func (c *Conn) reserveLimited(ctx context.Context, tubes []backend.Tube, timeout int64) (*backend.Job, error) {
+	var deadline time.Time
+
+	if timeout >= 0 {
+		deadline = time.Now().Add(time.Second * time.Duration(timeout))
+	}
+
+	for {
+		var (
+			available []backend.Tube
+			wait      time.Duration
+		)
+
+		for _, t := range tubes {
+			d := c.server.limiter.CheckTube(c.request(acl.ActionReserve, t.Name()))
+
+			switch {
+			case d.Reject:
+			case d.Delay > 0:
+				if wait == 0 || d.Delay < wait {
+					wait = d.Delay
+				}
+			default:
+				available = append(available, t)
+			}
+		}
+
+		if len(available) == 0 && wait == 0 {
+			c.logger.Debug("Rate limit exceeded on all tubes, rejecting", "action", acl.ActionReserve)
+
+			return nil, beanstalk.ErrThrottled
+		}
+
+		remaining := timeout
+
+		if !deadline.IsZero() {
+			remaining = max(int64(time.Until(deadline).Round(time.Second)/time.Second), 0)
+		}
+
+		if len(available) == 0 {
+			expired := !deadline.IsZero() && time.Until(deadline) < wait
+			if expired {
+				wait = max(time.Until(deadline), 0)
+			}
+
+			c.logger.Debug("Rate limit exceeded on all tubes, delaying", "action", acl.ActionReserve, "delay", wait)
+
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(wait):
+			}
+
+			if expired {
+				return nil, beanstalk.ErrReserveTimeout
+			}
+
+			continue
+		}
+
+		if wait > 0 {
+			// Check the delayed tubes again once they are expected to have a token
+			waitSecs := int64((wait + time.Second - 1) / time.Second)
+
+			if remaining < 0 || waitSecs < remaining {
+				remaining = waitSecs
+			}
+		}
+
+		job, err := c.backend.Reserve(ctx, available, remaining)
+		if errors.Is(err, beanstalk.ErrReserveTimeout) && wait > 0 &&
+			(deadline.IsZero() || time.Now().Before(deadline)) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		// The tube had a token when checked, so the decision is only recorded against the limit
+		c.server.limiter.TakeTube(c.request(acl.ActionReserve, job.Tube))
+
+		return job, nil
+	}
+}
+
+func (c *Conn) ReserveByID(ctx context.Context, id uint64) (uint64, []byte, error) {
+	ctx, span := c.startSpan(ctx, "ReserveByID", jobAttr(id))
+	defer span.End()
+
+	ctx = c.withOwner(ctx)
+
+	if err := c.checkJob(ctx, acl.ActionReserve, id); err != nil {
+		return 0, nil, err
+	}
+
+	job, err := c.backend.ReserveByID(ctx, id)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	c.setReserved(job.ID, job.Priority)
+
+	c.audit(audit.Event{Action: audit.ActionReserve, JobID: job.ID, Tube: job.Tube}, job.Data)
+
+	return job.ID, job.Data, nil
+}
+
+func (c *Conn) Delete(ctx context.Context, id uint64) error {
+	ctx, span := c.startSpan(ctx, "Delete", jobAttr(id))
+	defer span.End()
+
+	ctx = c.withOwner(ctx)
+
+	if err := c.checkJob(ctx, acl.ActionDelete, id); err != nil {
+		return err
+	}
+
+	tube := c.auditTube(ctx, id)
+
+	if err := c.backend.Delete(ctx, id); err != nil {
+		c.dropReserved(id, err)
+
+		return err
+	}
+
+	c.clearReserved(id)
+
+	c.audit(audit.Event{Action: audit.ActionDelete, JobID: id, Tube: tube}, nil)
+
+	return nil
+}
+
+func (c *Conn) Release(ctx context.Context, id uint64, pri uint64, delay uint64) error {
+	ctx, span := c.startSpan(ctx, "Release", jobAttr(id))
+	defer span.End()
+
+	ctx = c.withOwner(ctx)
+
+	if err := c.backend.Release(ctx, id, pri, delay); err != nil {
+		c.dropReserved(id, err)
+
+		return err
+	}
+
+	c.clearReserved(id)
+
+	return nil
+}
+
+func (c *Conn) Bury(ctx context.Context, id uint64, pri uint64) error {
+	ctx, span := c.startSpan(ctx, "Bury", jobAttr(id))
+	defer span.End()
+
+	ctx = c.withOwner(ctx)
+
+	tube := c.auditTube(ctx, id)
+
+	if err := c.backend.Bury(ctx, id, pri); err != nil {
+		c.dropReserved(id, err)
+
+		return err
+	}
+
+	c.clearReserved(id)
+
+	c.audit(audit.Event{Action: audit.ActionBury, JobID: id, Tube: tube}, nil)
+
+	return nil
+}
+
+func (c *Conn) Touch(ctx context.Context, id uint64) error {
+	ctx, span := c.startSpan(ctx, "Touch", jobAttr(id))
+	defer span.End()
+
+	ctx = c.withOwner(ctx)
+
+	if err := c.backend.Touch(ctx, id); err != nil {
+		c.dropReserved(id, err)
+
+		return err
+	}
+
+	return nil
+}
+
+func (c *Conn) Kick(ctx context.Context, bound uint64) (uint64, error) {
+	ctx, span := c.startSpan(ctx, "Kick", beanstalk.AttrTube.String(c.mainTube.Name()))
+	defer span.End()
+
+	if !c.allowed(acl.ActionKick, c.mainTube.Name()) {
+		return 0, beanstalk.ErrPermission
+	}
+
+	count, err := c.backend.Kick(ctx, c.mainTube, bound)
+	if err != nil {
+		return 0, err
+	}
+
+	if count > 0 {
+		c.audit(audit.Event{Action: audit.ActionKick, Count: count, Tube: c.mainTube.Name()}, nil)
+	}
+
+	return count, nil
+}
+
+func (c *Conn) KickJob(ctx context.Context, id uint64) error {
+	ctx, span := c.startSpan(ctx, "KickJob", jobAttr(id))
+	defer span.End()
+
+	if err := c.checkJob(ctx, acl.ActionKick, id); err != nil {
+		return err
+	}
+
+	tube := c.auditTube(ctx, id)
+
+	if err := c.backend.KickJob(ctx, id); err != nil {
+		return err
+	}
+
+	c.audit(audit.Event{Action: audit.ActionKick, JobID: id, Tube: tube}, nil)
+
+	return nil
+}
+
+func (c *Conn) PauseTube(ctx context.Context, tube string, delay uint64) error {
+	ctx, span := c.startSpan(ctx, "PauseTube", beanstalk.AttrTube.String(tube))
+	defer span.End()
+
+	if !c.allowed(acl.ActionPause, tube) {
+		return beanstalk.ErrPermission
+	}
+
+	t := c.backend.ResolveTube(tube)
+	defer t.Release()
+
+	return c.backend.PauseTube(ctx, t, delay)
+}
+
+// Close releases any jobs still reserved by the connection, as beanstalkd does when a client
+// disconnects.
+func (c *Conn) Close() error {
+	c.server.unregister(c)
+
+	ctx := c.withOwner(context.Background())
+
+	c.mu.Lock()
+	reserved := maps.Clone(c.reserved)
+	clear(c.reserved)
+	c.mu.Unlock()
+
+	for id, pri := range reserved {
+		// Jobs whose reservation expired are no longer owned by this connection
+		err := c.backend.Release(ctx, id, pri, 0)
+		if err != nil && !errors.Is(err, beanstalk.ErrNotFound) {
+			c.logger.Warn("Failed to release reserved job", "id", id, "err", err)
+		}
+	}
+
+	c.mainTube.Release()
+
+	for _, t := range c.watching {
+		t.Release()
+	}
+
+	return nil
+}
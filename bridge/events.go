@@ -0,0 +1,73 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+
+	"github.com/csnewman/beanbridge/acl"
+	"github.com/csnewman/beanbridge/beanstalk"
+	"github.com/csnewman/beanbridge/events"
+)
+
+// eventBufferSize is the number of events buffered for each subscriber before events are dropped.
+const eventBufferSize = 1024
+
+// Events returns the bus carrying job state transitions, if the backend publishes them.
+func (s *Server) Events() *events.Bus {
+	return s.events
+}
+
+func (c *Conn) SubscribeEvents(ctx context.Context, tubes []string, types []string) (iter.Seq[[]byte], error) {
+	parsed, err := events.ParseTypes(types)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", beanstalk.ErrBadFormat, err)
+	}
+
+	filter := events.Filter{
+		Tubes: tubes,
+		Types: parsed,
+	}
+
+	return func(yield func([]byte) bool) {
+		sub := c.server.events.Subscribe(filter, eventBufferSize)
+		defer sub.Close()
+
+		c.logger.Info("Client subscribed to events", "tubes", tubes, "types", types)
+
+		defer func() {
+			if dropped := sub.Dropped(); dropped > 0 {
+				c.logger.Warn("Dropped events for slow subscriber", "dropped", dropped)
+			}
+		}()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case e := <-sub.Events():
+				if !c.mayWatch(e.Tube) {
+					continue
+				}
+
+				data, err := json.Marshal(e)
+				if err != nil {
+					c.logger.Error("Failed to encode event", "err", err)
+
+					continue
+				}
+
+				if !yield(data) {
+					return
+				}
+			}
+		}
+	}, nil
+}
+
+// mayWatch checks whether the client may watch a tube, without logging denials, so that events can
+// be filtered quietly.
+func (c *Conn) mayWatch(tube string) bool {
+	return c.permitted(acl.ActionWatch, tube)
+}
@@ -0,0 +1,89 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/csnewman/beanbridge/backend"
+)
+
+const healthCheckTimeout = 5 * time.Second
+
+var (
+	ErrNotReadyDraining     = errors.New("draining")
+	ErrNotReadyShuttingDown = errors.New("shutting down")
+)
+
+// HealthConfig serves liveness and readiness probes over HTTP on Address, at /healthz and
+// /readyz. The address may be shared with the metrics endpoint and admin API.
+type HealthConfig struct {
+	Address string `yaml:"address"`
+}
+
+func (c *HealthConfig) validate(field string) error {
+	var errs []error
+
+	if c.Address == "" {
+		errs = append(errs, fmt.Errorf("%s.address: must not be empty", field))
+	} else if _, _, err := net.SplitHostPort(c.Address); err != nil {
+		errs = append(errs, fmt.Errorf("%s.address: %w", field, err))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Ready reports whether the bridge should receive new traffic. It fails while draining or
+// shutting down, or if the backend implements backend.HealthChecker and reports an error.
+func (s *Server) Ready(ctx context.Context) error {
+	if s.shuttingDown.Load() {
+		return ErrNotReadyShuttingDown
+	}
+
+	if s.draining.Load() {
+		return ErrNotReadyDraining
+	}
+
+	checker, ok := s.backend.(backend.HealthChecker)
+	if !ok {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	if err := checker.Health(ctx); err != nil {
+		return fmt.Errorf("backend unhealthy: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, _ *http.Request) {
+	writeProbe(w, http.StatusOK, "ok")
+}
+
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	if err := s.Ready(r.Context()); err != nil {
+		if !errors.Is(err, ErrNotReadyDraining) && !errors.Is(err, ErrNotReadyShuttingDown) {
+			s.logger.Warn("Readiness check failed", "err", err)
+		}
+
+		writeProbe(w, http.StatusServiceUnavailable, err.Error())
+
+		return
+	}
+
+	writeProbe(w, http.StatusOK, "ok")
+}
+
+func writeProbe(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+
+	_, _ = fmt.Fprintln(w, msg)
+}
@@ -0,0 +1,107 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/csnewman/beanbridge/beanstalk"
+)
+
+const httpReadHeaderTimeout = 10 * time.Second
+
+// httpServer serves auxiliary HTTP endpoints, such as metrics and the admin API, on a separate
+// listener.
+type httpServer struct {
+	logger  *slog.Logger
+	name    string
+	address string
+	l       net.Listener
+	mux     *http.ServeMux
+	srv     *http.Server
+	// cancel ends long lived requests, such as event streams, once shutdown begins
+	cancel context.CancelFunc
+}
+
+// newHTTPServer listens on address, using a matching inherited listener if one is available.
+func newHTTPServer(logger *slog.Logger, name string, address string, inherited *[]net.Listener) (*httpServer, error) {
+	var l net.Listener
+
+	for i, il := range *inherited {
+		if beanstalk.AddrMatches("tcp", address, il.Addr()) {
+			l = il
+			*inherited = slices.Delete(*inherited, i, i+1)
+
+			break
+		}
+	}
+
+	if l == nil {
+		var err error
+
+		l, err = net.Listen("tcp", address)
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen for %s: %w", name, err)
+		}
+	}
+
+	mux := http.NewServeMux()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &httpServer{
+		logger:  logger,
+		name:    name,
+		address: address,
+		l:       l,
+		mux:     mux,
+		srv: &http.Server{
+			Handler:           mux,
+			ReadHeaderTimeout: httpReadHeaderTimeout,
+			ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelWarn),
+			BaseContext: func(net.Listener) context.Context {
+				return ctx
+			},
+		},
+		cancel: cancel,
+	}, nil
+}
+
+func (h *httpServer) serve() {
+	h.logger.Info("Listening for HTTP connections", "server", h.name, "addr", h.l.Addr().String())
+
+	if err := h.srv.Serve(h.l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		h.logger.Error("HTTP server failed", "server", h.name, "err", err)
+	}
+}
+
+func (h *httpServer) shutdown(ctx context.Context) error {
+	h.cancel()
+
+	err := h.srv.Shutdown(ctx)
+
+	// Shutdown only closes the listener if Serve was called
+	_ = h.l.Close()
+
+	if err != nil {
+		return fmt.Errorf("failed to shutdown %s: %w", h.name, err)
+	}
+
+	return nil
+}
+
+func (h *httpServer) close() {
+	h.cancel()
+
+	_ = h.srv.Close()
+	_ = h.l.Close()
+}
+
+func (h *httpServer) file() (*os.File, error) {
+	return beanstalk.ListenerFile(h.l)
+}
@@ -0,0 +1,202 @@
+package bridge
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/netip"
+	"os/user"
+	"strconv"
+	"strings"
+
+	"github.com/csnewman/beanbridge/beanstalk"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	ListenerTCP    = "tcp"
+	ListenerTCPTLS = "tcp+tls"
+	ListenerUnix   = "unix"
+)
+
+// ListenerConfig configures an additional beanstalk listener. Type is one of tcp, tcp+tls or unix.
+// Mode, Owner and Group only apply to unix sockets, where Owner and Group accept names or ids.
+// TrustedProxies lists the addresses or CIDRs allowed to send PROXY protocol headers, and must be
+// set when ProxyProtocol is enabled.
+type ListenerConfig struct {
+	Type           string     `yaml:"type"`
+	Address        string     `yaml:"address"`
+	TLS            *TLSConfig `yaml:"tls"`
+	MaxConnections int        `yaml:"max-connections"`
+	Mode           FileMode   `yaml:"mode"`
+	Owner          string     `yaml:"owner"`
+	Group          string     `yaml:"group"`
+	ProxyProtocol  bool       `yaml:"proxy-protocol"`
+	TrustedProxies []string   `yaml:"trusted-proxies"`
+}
+
+// FileMode is a file permission, written in octal.
+type FileMode uint32
+
+func (m *FileMode) UnmarshalYAML(node *yaml.Node) error {
+	raw := strings.TrimPrefix(strings.TrimPrefix(node.Value, "0o"), "0O")
+
+	v, err := strconv.ParseUint(raw, 8, 32)
+	if err != nil {
+		return fmt.Errorf("invalid file mode %q: %w", node.Value, err)
+	}
+
+	*m = FileMode(v)
+
+	return nil
+}
+
+func (c *ListenerConfig) validate(field string) error {
+	var errs []error
+
+	if c.Address == "" {
+		errs = append(errs, fmt.Errorf("%s.address: must not be empty", field))
+	}
+
+	switch c.Type {
+	case ListenerTCP, ListenerTCPTLS:
+		if c.Address != "" {
+			if _, _, err := net.SplitHostPort(c.Address); err != nil {
+				errs = append(errs, fmt.Errorf("%s.address: %w", field, err))
+			}
+		}
+
+		if c.Mode != 0 || c.Owner != "" || c.Group != "" {
+			errs = append(errs, fmt.Errorf("%s: mode, owner and group only apply to unix listeners", field))
+		}
+	case ListenerUnix:
+		if _, err := lookupID(c.Owner, user.Lookup, func(u *user.User) string { return u.Uid }); err != nil {
+			errs = append(errs, fmt.Errorf("%s.owner: %w", field, err))
+		}
+
+		if _, err := lookupID(c.Group, user.LookupGroup, func(g *user.Group) string { return g.Gid }); err != nil {
+			errs = append(errs, fmt.Errorf("%s.group: %w", field, err))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("%s.type: unknown type %q, expected tcp, tcp+tls or unix", field, c.Type))
+	}
+
+	if c.Type == ListenerTCPTLS {
+		if c.TLS == nil {
+			errs = append(errs, fmt.Errorf("%s.tls: required for tcp+tls listeners", field))
+		} else if err := c.TLS.validate(field + ".tls"); err != nil {
+			errs = append(errs, err)
+		}
+	} else if c.TLS != nil {
+		errs = append(errs, fmt.Errorf("%s.tls: only applies to tcp+tls listeners", field))
+	}
+
+	if c.MaxConnections < 0 {
+		errs = append(errs, fmt.Errorf("%s.max-connections: must not be negative", field))
+	}
+
+	if _, err := parsePrefixes(c.TrustedProxies); err != nil {
+		errs = append(errs, fmt.Errorf("%s.trusted-proxies: %w", field, err))
+	} else if len(c.TrustedProxies) > 0 && !c.ProxyProtocol {
+		errs = append(errs, fmt.Errorf("%s.trusted-proxies: requires proxy-protocol to be enabled", field))
+	} else if len(c.TrustedProxies) == 0 && c.ProxyProtocol {
+		errs = append(errs, fmt.Errorf("%s.trusted-proxies: required when proxy-protocol is enabled", field))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (c *ListenerConfig) build() (beanstalk.ListenerConfig, error) {
+	trusted, err := parsePrefixes(c.TrustedProxies)
+	if err != nil {
+		return beanstalk.ListenerConfig{}, err
+	}
+
+	cfg := beanstalk.ListenerConfig{
+		Network:        ListenerTCP,
+		Address:        c.Address,
+		MaxConnections: c.MaxConnections,
+		ProxyProtocol:  c.ProxyProtocol,
+		TrustedProxies: trusted,
+	}
+
+	switch c.Type {
+	case ListenerTCPTLS:
+		tlsConfig, err := c.TLS.Build()
+		if err != nil {
+			return cfg, err
+		}
+
+		cfg.TLSConfig = tlsConfig
+	case ListenerUnix:
+		cfg.Network = ListenerUnix
+		cfg.FileMode = fs.FileMode(c.Mode)
+
+		uid, err := lookupID(c.Owner, user.Lookup, func(u *user.User) string { return u.Uid })
+		if err != nil {
+			return cfg, err
+		}
+
+		gid, err := lookupID(c.Group, user.LookupGroup, func(g *user.Group) string { return g.Gid })
+		if err != nil {
+			return cfg, err
+		}
+
+		cfg.UID = uid
+		cfg.GID = gid
+	}
+
+	return cfg, nil
+}
+
+// lookupID resolves a user or group, given either by name or numeric id. Empty values resolve to
+// nil.
+func lookupID[T any](name string, lookup func(string) (T, error), id func(T) string) (*int, error) {
+	if name == "" {
+		return nil, nil
+	}
+
+	if n, err := strconv.Atoi(name); err == nil {
+		return &n, nil
+	}
+
+	v, err := lookup(name)
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := strconv.Atoi(id(v))
+	if err != nil {
+		return nil, err
+	}
+
+	return &n, nil
+}
+
+// parsePrefixes parses a list of CIDRs, where bare addresses are treated as single host prefixes.
+func parsePrefixes(raw []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(raw))
+
+	for _, r := range raw {
+		if !strings.Contains(r, "/") {
+			addr, err := netip.ParseAddr(r)
+			if err != nil {
+				return nil, err
+			}
+
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(r)
+		if err != nil {
+			return nil, err
+		}
+
+		prefixes = append(prefixes, prefix.Masked())
+	}
+
+	return prefixes, nil
+}
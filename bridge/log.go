@@ -0,0 +1,118 @@
+package bridge
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/csnewman/beanbridge/beanstalk"
+)
+
+const (
+	defaultLogSamplingInterval   = time.Second
+	defaultLogSamplingFirst      = 100
+	defaultLogSamplingThereafter = 100
+)
+
+// LogConfig controls log output. Job bodies are only logged, at debug level, if Bodies is set.
+type LogConfig struct {
+	Level    string             `yaml:"level"`
+	Format   string             `yaml:"format"`
+	Source   bool               `yaml:"source"`
+	Bodies   bool               `yaml:"bodies"`
+	Sampling *LogSamplingConfig `yaml:"sampling"`
+}
+
+// LogSamplingConfig limits the debug logging of commands. Within each interval, the first commands
+// of each type are logged, and then only every thereafter-th command. Unset fields take their
+// defaults, so that an empty config still logs a sample.
+type LogSamplingConfig struct {
+	Interval   time.Duration `yaml:"interval"`
+	First      *int          `yaml:"first"`
+	Thereafter *int          `yaml:"thereafter"`
+}
+
+func (c *LogSamplingConfig) first() int {
+	if c.First == nil {
+		return defaultLogSamplingFirst
+	}
+
+	return *c.First
+}
+
+func (c *LogSamplingConfig) thereafter() int {
+	if c.Thereafter == nil {
+		return defaultLogSamplingThereafter
+	}
+
+	return *c.Thereafter
+}
+
+func (c *LogConfig) validate(field string) error {
+	var errs []error
+
+	if c.Level != "" {
+		if _, err := c.ParseLevel(); err != nil {
+			errs = append(errs, fmt.Errorf("%s.level: %w", field, err))
+		}
+	}
+
+	if c.Format != "" && c.Format != "text" && c.Format != "json" {
+		errs = append(errs, fmt.Errorf("%s.format: must be text or json", field))
+	}
+
+	if c.Sampling != nil {
+		if c.Sampling.Interval < 0 {
+			errs = append(errs, fmt.Errorf("%s.sampling.interval: must not be negative", field))
+		}
+
+		if c.Sampling.first() < 0 {
+			errs = append(errs, fmt.Errorf("%s.sampling.first: must not be negative", field))
+		}
+
+		if c.Sampling.thereafter() < 0 {
+			errs = append(errs, fmt.Errorf("%s.sampling.thereafter: must not be negative", field))
+		}
+
+		if c.Sampling.first() == 0 && c.Sampling.thereafter() == 0 {
+			errs = append(errs, fmt.Errorf("%s.sampling: first and thereafter must not both be zero", field))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// ParseLevel returns the configured level, defaulting to info.
+func (c *LogConfig) ParseLevel() (slog.Level, error) {
+	var level slog.Level
+
+	if c.Level == "" {
+		return slog.LevelInfo, nil
+	}
+
+	if err := level.UnmarshalText([]byte(c.Level)); err != nil {
+		return 0, err
+	}
+
+	return level, nil
+}
+
+func (c *LogConfig) beanstalkOptions() []beanstalk.Option {
+	var opts []beanstalk.Option
+
+	if c.Bodies {
+		opts = append(opts, beanstalk.WithLogBodies())
+	}
+
+	if c.Sampling != nil {
+		interval := c.Sampling.Interval
+		if interval == 0 {
+			interval = defaultLogSamplingInterval
+		}
+
+		opts = append(opts, beanstalk.WithLogSampling(interval, c.Sampling.first(), c.Sampling.thereafter()))
+	}
+
+	return opts
+}
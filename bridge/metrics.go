@@ -0,0 +1,55 @@
+package bridge
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+const DefaultMetricsPath = "/metrics"
+
+// MetricsConfig serves Prometheus metrics over HTTP on Address, under Path.
+type MetricsConfig struct {
+	Address string `yaml:"address"`
+	Path    string `yaml:"path"`
+}
+
+func (c *MetricsConfig) validate(field string) error {
+	var errs []error
+
+	if c.Address == "" {
+		errs = append(errs, fmt.Errorf("%s.address: must not be empty", field))
+	} else if _, _, err := net.SplitHostPort(c.Address); err != nil {
+		errs = append(errs, fmt.Errorf("%s.address: %w", field, err))
+	}
+
+	if c.Path != "" && !strings.HasPrefix(c.Path, "/") {
+		errs = append(errs, fmt.Errorf("%s.path: must start with /", field))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (c *MetricsConfig) path() string {
+	if c.Path == "" {
+		return DefaultMetricsPath
+	}
+
+	return c.Path
+}
+
+// newMetricsRegistry creates a registry including the standard Go runtime and process metrics.
+func newMetricsRegistry() *prometheus.Registry {
+	reg := prometheus.NewRegistry()
+
+	reg.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	return reg
+}
@@ -0,0 +1,80 @@
+package bridge
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+
+	"github.com/csnewman/beanbridge/acl"
+	"github.com/csnewman/beanbridge/ratelimit"
+)
+
+// RateLimitConfig limits puts and reserves to Rate per second, allowing bursts of up to Burst.
+// Per is either client (the default), giving each principal or address its own limit, or tube,
+// giving each tube a limit shared by all clients. Mode is one of delay (the default), reject or
+// bury, where bury only applies to puts. The remaining fields select requests as in ACLRule.
+type RateLimitConfig struct {
+	Actions    []string `yaml:"actions"`
+	Principals []string `yaml:"principals"`
+	Sources    []string `yaml:"sources"`
+	Tubes      []string `yaml:"tubes"`
+	Per        string   `yaml:"per"`
+	Rate       float64  `yaml:"rate"`
+	Burst      int      `yaml:"burst"`
+	Mode       string   `yaml:"mode"`
+}
+
+func (c *RateLimitConfig) validate(field string) error {
+	var errs []error
+
+	sources, err := parsePrefixes(c.Sources)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("%s.sources: %w", field, err))
+	}
+
+	rule := c.rule(sources)
+
+	if err := rule.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("%s: %w", field, err))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (c *RateLimitConfig) build() (ratelimit.Rule, error) {
+	sources, err := parsePrefixes(c.Sources)
+	if err != nil {
+		return ratelimit.Rule{}, err
+	}
+
+	return c.rule(sources), nil
+}
+
+func (c *RateLimitConfig) rule(sources []netip.Prefix) ratelimit.Rule {
+	actions := make([]acl.Action, 0, len(c.Actions))
+
+	for _, a := range c.Actions {
+		actions = append(actions, acl.Action(a))
+	}
+
+	rule := ratelimit.Rule{
+		Actions:    actions,
+		Principals: c.Principals,
+		Sources:    sources,
+		Tubes:      c.Tubes,
+		Scope:      ratelimit.Scope(c.Per),
+		Rate:       c.Rate,
+		Burst:      c.Burst,
+		Mode:       ratelimit.Mode(c.Mode),
+	}
+
+	if rule.Scope == "" {
+		rule.Scope = ratelimit.ScopeClient
+	}
+
+	if rule.Mode == "" {
+		rule.Mode = ratelimit.ModeDelay
+	}
+
+	return rule
+}
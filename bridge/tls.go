@@ -0,0 +1,163 @@
+package bridge
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+)
+
+var (
+	ErrInvalidTLSConfig = errors.New("invalid tls config")
+
+	tlsVersions = map[string]uint16{
+		"1.0": tls.VersionTLS10,
+		"1.1": tls.VersionTLS11,
+		"1.2": tls.VersionTLS12,
+		"1.3": tls.VersionTLS13,
+	}
+
+	tlsClientAuthTypes = map[string]tls.ClientAuthType{
+		"none":               tls.NoClientCert,
+		"request":            tls.RequestClientCert,
+		"require":            tls.RequireAnyClientCert,
+		"verify-if-given":    tls.VerifyClientCertIfGiven,
+		"require-and-verify": tls.RequireAndVerifyClientCert,
+	}
+)
+
+// TLSConfig configures TLS for the beanstalk listener. ClientAuth is one of none, request, require,
+// verify-if-given or require-and-verify, defaulting to require-and-verify when a client CA is
+// configured and none otherwise.
+type TLSConfig struct {
+	CertFile     string   `yaml:"cert-file"`
+	KeyFile      string   `yaml:"key-file"`
+	ClientCAFile string   `yaml:"client-ca-file"`
+	ClientAuth   string   `yaml:"client-auth"`
+	MinVersion   string   `yaml:"min-version"`
+	CipherSuites []string `yaml:"cipher-suites"`
+}
+
+func (c *TLSConfig) validate(field string) error {
+	var errs []error
+
+	if c.CertFile == "" {
+		errs = append(errs, fmt.Errorf("%s.cert-file: must not be empty", field))
+	}
+
+	if c.KeyFile == "" {
+		errs = append(errs, fmt.Errorf("%s.key-file: must not be empty", field))
+	}
+
+	if _, err := c.clientAuth(); err != nil {
+		errs = append(errs, fmt.Errorf("%s.client-auth: %w", field, err))
+	}
+
+	if _, err := c.minVersion(); err != nil {
+		errs = append(errs, fmt.Errorf("%s.min-version: %w", field, err))
+	}
+
+	if _, err := c.cipherSuites(); err != nil {
+		errs = append(errs, fmt.Errorf("%s.cipher-suites: %w", field, err))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (c *TLSConfig) clientAuth() (tls.ClientAuthType, error) {
+	if c.ClientAuth == "" {
+		if c.ClientCAFile != "" {
+			return tls.RequireAndVerifyClientCert, nil
+		}
+
+		return tls.NoClientCert, nil
+	}
+
+	auth, ok := tlsClientAuthTypes[c.ClientAuth]
+	if !ok {
+		return 0, fmt.Errorf("%w: unknown client auth type %q", ErrInvalidTLSConfig, c.ClientAuth)
+	}
+
+	if c.ClientCAFile == "" && (auth == tls.VerifyClientCertIfGiven || auth == tls.RequireAndVerifyClientCert) {
+		return 0, fmt.Errorf("%w: client auth %q requires a client-ca-file", ErrInvalidTLSConfig, c.ClientAuth)
+	}
+
+	return auth, nil
+}
+
+func (c *TLSConfig) minVersion() (uint16, error) {
+	if c.MinVersion == "" {
+		return tls.VersionTLS12, nil
+	}
+
+	v, ok := tlsVersions[c.MinVersion]
+	if !ok {
+		return 0, fmt.Errorf("%w: unknown version %q, expected 1.0, 1.1, 1.2 or 1.3", ErrInvalidTLSConfig, c.MinVersion)
+	}
+
+	return v, nil
+}
+
+func (c *TLSConfig) cipherSuites() ([]uint16, error) {
+	if len(c.CipherSuites) == 0 {
+		return nil, nil
+	}
+
+	suites := slices.Concat(tls.CipherSuites(), tls.InsecureCipherSuites())
+	ids := make([]uint16, 0, len(c.CipherSuites))
+
+	for _, name := range c.CipherSuites {
+		i := slices.IndexFunc(suites, func(s *tls.CipherSuite) bool {
+			return s.Name == name
+		})
+		if i < 0 {
+			return nil, fmt.Errorf("%w: unknown cipher suite %q", ErrInvalidTLSConfig, name)
+		}
+
+		ids = append(ids, suites[i].ID)
+	}
+
+	return ids, nil
+}
+
+// Build loads the referenced certificates and creates the server side tls.Config.
+func (c *TLSConfig) Build() (*tls.Config, error) {
+	if err := c.validate("tls"); err != nil {
+		return nil, err
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load certificate: %w", err)
+	}
+
+	clientAuth, _ := c.clientAuth()
+	minVersion, _ := c.minVersion()
+	cipherSuites, _ := c.cipherSuites()
+
+	cfg := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   clientAuth,
+		MinVersion:   minVersion,
+		CipherSuites: cipherSuites,
+	}
+
+	if c.ClientCAFile != "" {
+		raw, err := os.ReadFile(c.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client ca: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+
+		if !pool.AppendCertsFromPEM(raw) {
+			return nil, fmt.Errorf("%w: no certificates found in %s", ErrInvalidTLSConfig, c.ClientCAFile)
+		}
+
+		cfg.ClientCAs = pool
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,311 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+
+	"github.com/csnewman/beanbridge/backend"
+	"github.com/csnewman/beanbridge/beanstalk"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	tracerName         = "github.com/csnewman/beanbridge/bridge"
+	defaultServiceName = "beanbridge"
+
+	attrPrincipal = attribute.Key("beanbridge.principal")
+
+	TracingExporterOTLP   = "otlp"
+	TracingExporterStdout = "stdout"
+)
+
+// TracingConfig exports OpenTelemetry traces, either over OTLP/HTTP to Endpoint, or to stdout.
+// The OTLP endpoint, headers and TLS settings can also be given through the standard
+// OTEL_EXPORTER_OTLP_* environment variables. SampleRatio defaults to sampling every trace.
+type TracingConfig struct {
+	Exporter    string            `yaml:"exporter"`
+	Endpoint    string            `yaml:"endpoint"`
+	Insecure    bool              `yaml:"insecure"`
+	Headers     map[string]string `yaml:"headers"`
+	ServiceName string            `yaml:"service-name"`
+	SampleRatio *float64          `yaml:"sample-ratio"`
+}
+
+func (c *TracingConfig) validate(field string) error {
+	var errs []error
+
+	if c.Exporter != TracingExporterOTLP && c.Exporter != TracingExporterStdout {
+		errs = append(errs, fmt.Errorf("%s.exporter: must be %s or %s", field, TracingExporterOTLP, TracingExporterStdout))
+	}
+
+	if c.SampleRatio != nil && (*c.SampleRatio < 0 || *c.SampleRatio > 1) {
+		errs = append(errs, fmt.Errorf("%s.sample-ratio: must be between 0 and 1", field))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (c *TracingConfig) build(ctx context.Context) (*sdktrace.TracerProvider, error) {
+	var (
+		exporter sdktrace.SpanExporter
+		err      error
+	)
+
+	switch c.Exporter {
+	case TracingExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	default:
+		var opts []otlptracehttp.Option
+
+		if c.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(c.Endpoint))
+		}
+
+		if c.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+
+		if len(c.Headers) > 0 {
+			opts = append(opts, otlptracehttp.WithHeaders(c.Headers))
+		}
+
+		exporter, err = otlptracehttp.New(ctx, opts...)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+
+	serviceName := c.ServiceName
+	if serviceName == "" {
+		serviceName = defaultServiceName
+	}
+
+	res, err := resource.Merge(
+		resource.Default(),
+		resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace resource: %w", err)
+	}
+
+	ratio := 1.0
+	if c.SampleRatio != nil {
+		ratio = *c.SampleRatio
+	}
+
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	), nil
+}
+
+// tracedBackend wraps the job operations of a backend in spans, and carries the trace context of
+// producers along with jobs, so that consumer spans link back to them.
+type tracedBackend struct {
+	backend.Backend
+
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+func (b *tracedBackend) start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return b.tracer.Start(ctx, "backend."+name, trace.WithAttributes(attrs...))
+}
+
+func (b *tracedBackend) Put(ctx context.Context, tube backend.Tube, pri uint64, delay uint64, ttr uint64, data []byte) (uint64, bool, error) {
+	ctx, span := b.start(ctx, "Put", beanstalk.AttrTube.String(tube.Name()), beanstalk.AttrJobBytes.Int(len(data)))
+	defer span.End()
+
+	md := maps.Clone(backend.MetadataFromContext(ctx))
+	if md == nil {
+		md = make(backend.Metadata)
+	}
+
+	b.propagator.Inject(ctx, propagation.MapCarrier(md))
+
+	id, buried, err := b.Backend.Put(backend.WithMetadata(ctx, md), tube, pri, delay, ttr, data)
+	if err != nil {
+		endSpan(span, err)
+
+		return 0, false, err
+	}
+
+	span.SetAttributes(jobAttr(id))
+
+	return id, buried, nil
+}
+
+func (b *tracedBackend) Reserve(ctx context.Context, tubes []backend.Tube, timeout int64) (*backend.Job, error) {
+	ctx, span := b.start(ctx, "Reserve", attribute.Int64("beanstalk.timeout", timeout))
+	defer span.End()
+
+	job, err := b.Backend.Reserve(ctx, tubes, timeout)
+	endSpan(span, err)
+
+	b.linkJob(span, job)
+
+	return job, err
+}
+
+func (b *tracedBackend) ReserveByID(ctx context.Context, id uint64) (*backend.Job, error) {
+	ctx, span := b.start(ctx, "ReserveByID", jobAttr(id))
+	defer span.End()
+
+	job, err := b.Backend.ReserveByID(ctx, id)
+	endSpan(span, err)
+
+	b.linkJob(span, job)
+
+	return job, err
+}
+
+func (b *tracedBackend) Delete(ctx context.Context, id uint64) error {
+	ctx, span := b.start(ctx, "Delete", jobAttr(id))
+	defer span.End()
+
+	err := b.Backend.Delete(ctx, id)
+	endSpan(span, err)
+
+	return err
+}
+
+func (b *tracedBackend) Release(ctx context.Context, id uint64, pri uint64, delay uint64) error {
+	ctx, span := b.start(ctx, "Release", jobAttr(id))
+	defer span.End()
+
+	err := b.Backend.Release(ctx, id, pri, delay)
+	endSpan(span, err)
+
+	return err
+}
+
+func (b *tracedBackend) Bury(ctx context.Context, id uint64, pri uint64) error {
+	ctx, span := b.start(ctx, "Bury", jobAttr(id))
+	defer span.End()
+
+	err := b.Backend.Bury(ctx, id, pri)
+	endSpan(span, err)
+
+	return err
+}
+
+func (b *tracedBackend) Touch(ctx context.Context, id uint64) error {
+	ctx, span := b.start(ctx, "Touch", jobAttr(id))
+	defer span.End()
+
+	err := b.Backend.Touch(ctx, id)
+	endSpan(span, err)
+
+	return err
+}
+
+func (b *tracedBackend) Peek(ctx context.Context, id uint64) (*backend.Job, error) {
+	ctx, span := b.start(ctx, "Peek", jobAttr(id))
+	defer span.End()
+
+	job, err := b.Backend.Peek(ctx, id)
+	endSpan(span, err)
+
+	return job, err
+}
+
+func (b *tracedBackend) Kick(ctx context.Context, tube backend.Tube, bound uint64) (uint64, error) {
+	ctx, span := b.start(ctx, "Kick", beanstalk.AttrTube.String(tube.Name()))
+	defer span.End()
+
+	kicked, err := b.Backend.Kick(ctx, tube, bound)
+	endSpan(span, err)
+
+	return kicked, err
+}
+
+func (b *tracedBackend) KickJob(ctx context.Context, id uint64) error {
+	ctx, span := b.start(ctx, "KickJob", jobAttr(id))
+	defer span.End()
+
+	err := b.Backend.KickJob(ctx, id)
+	endSpan(span, err)
+
+	return err
+}
+
+func (b *tracedBackend) BuryJob(ctx context.Context, id uint64) error {
+	ctx, span := b.start(ctx, "BuryJob", jobAttr(id))
+	defer span.End()
+
+	err := b.Backend.BuryJob(ctx, id)
+	endSpan(span, err)
+
+	return err
+}
+
+func (b *tracedBackend) PauseTube(ctx context.Context, tube backend.Tube, delay uint64) error {
+	ctx, span := b.start(ctx, "PauseTube", beanstalk.AttrTube.String(tube.Name()))
+	defer span.End()
+
+	err := b.Backend.PauseTube(ctx, tube, delay)
+	endSpan(span, err)
+
+	return err
+}
+
+// linkJob records the reserved job on the span, linking it to the span that put the job.
+func (b *tracedBackend) linkJob(span trace.Span, job *backend.Job) {
+	if job == nil {
+		return
+	}
+
+	span.SetAttributes(
+		jobAttr(job.ID),
+		beanstalk.AttrTube.String(job.Tube),
+		beanstalk.AttrJobBytes.Int(len(job.Data)),
+	)
+
+	if len(job.Metadata) == 0 {
+		return
+	}
+
+	producer := trace.SpanContextFromContext(
+		b.propagator.Extract(context.Background(), propagation.MapCarrier(job.Metadata)),
+	)
+
+	if producer.IsValid() {
+		span.AddLink(trace.Link{SpanContext: producer})
+	}
+}
+
+// endSpan records err on the span. Reserve timeouts are expected, so are not treated as errors.
+func endSpan(span trace.Span, err error) {
+	if err == nil || errors.Is(err, beanstalk.ErrReserveTimeout) || errors.Is(err, context.Canceled) {
+		return
+	}
+
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// startSpan starts a span for a bridge operation, recording the principal of the client.
+func (c *Conn) startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if c.principal != nil {
+		attrs = append(attrs, attrPrincipal.String(c.principal.Name))
+	}
+
+	return c.server.tracer.Start(ctx, "bridge."+name, trace.WithAttributes(attrs...))
+}
+
+func jobAttr(id uint64) attribute.KeyValue {
+	return beanstalk.AttrJobID.Int64(int64(id))
+}
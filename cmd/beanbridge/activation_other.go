@@ -0,0 +1,24 @@
+//go:build !unix
+
+package main
+
+import (
+	"errors"
+	"net"
+
+	"github.com/csnewman/beanbridge/bridge"
+)
+
+var errRestartUnsupported = errors.New("hot restart is not supported on this platform")
+
+func inheritedListeners() ([]net.Listener, error) {
+	return nil, nil
+}
+
+func startReplacement(_ *bridge.Server) (int, error) {
+	return 0, errRestartUnsupported
+}
+
+func notifyReady() error {
+	return nil
+}
@@ -0,0 +1,107 @@
+//go:build unix
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+const (
+	// listenFDsStart is the first file descriptor passed by systemd, or by a parent process during
+	// a hot restart.
+	listenFDsStart = 3
+	// inheritFDsEnv holds the number of listeners passed by a parent process during a hot restart.
+	inheritFDsEnv = envPrefix + "INHERIT_FDS"
+)
+
+var errInvalidActivation = errors.New("invalid socket activation environment")
+
+// inheritedListeners returns listeners passed in by systemd socket activation (LISTEN_FDS) or by a
+// parent process performing a hot restart. The environment variables are cleared, so that they are
+// not passed on to child processes.
+func inheritedListeners() ([]net.Listener, error) {
+	count, names, err := inheritedFDs()
+	if err != nil || count == 0 {
+		return nil, err
+	}
+
+	listeners := make([]net.Listener, 0, count)
+
+	for i := range count {
+		fd := listenFDsStart + i
+
+		name := "listen-fd-" + strconv.Itoa(fd)
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+
+		syscall.CloseOnExec(fd)
+
+		f := os.NewFile(uintptr(fd), name)
+
+		l, err := net.FileListener(f)
+
+		_ = f.Close()
+
+		if err != nil {
+			for _, l := range listeners {
+				_ = l.Close()
+			}
+
+			return nil, fmt.Errorf("failed to use inherited fd %d (%s): %w", fd, name, err)
+		}
+
+		listeners = append(listeners, l)
+	}
+
+	return listeners, nil
+}
+
+func inheritedFDs() (int, []string, error) {
+	if raw, ok := os.LookupEnv(inheritFDsEnv); ok {
+		_ = os.Unsetenv(inheritFDsEnv)
+
+		count, err := strconv.Atoi(raw)
+		if err != nil || count < 0 {
+			return 0, nil, fmt.Errorf("%w: %s=%q", errInvalidActivation, inheritFDsEnv, raw)
+		}
+
+		return count, nil, nil
+	}
+
+	rawPID, ok := os.LookupEnv("LISTEN_PID")
+	if !ok {
+		return 0, nil, nil
+	}
+
+	rawFDs := os.Getenv("LISTEN_FDS")
+	rawNames := os.Getenv("LISTEN_FDNAMES")
+
+	_ = os.Unsetenv("LISTEN_PID")
+	_ = os.Unsetenv("LISTEN_FDS")
+	_ = os.Unsetenv("LISTEN_FDNAMES")
+
+	// The variables are intended for another process, e.g. when run from a wrapper script
+	if pid, err := strconv.Atoi(rawPID); err != nil || pid != os.Getpid() {
+		return 0, nil, nil
+	}
+
+	count, err := strconv.Atoi(rawFDs)
+	if err != nil || count < 0 {
+		return 0, nil, fmt.Errorf("%w: LISTEN_FDS=%q", errInvalidActivation, rawFDs)
+	}
+
+	var names []string
+
+	if rawNames != "" {
+		names = strings.Split(rawNames, ":")
+	}
+
+	return count, names, nil
+}
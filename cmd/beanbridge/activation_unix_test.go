@@ -0,0 +1,140 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInheritedFDs(t *testing.T) {
+	pid := strconv.Itoa(os.Getpid())
+
+	tests := []struct {
+		name  string
+		env   map[string]string
+		count int
+		names []string
+		err   string
+	}{
+		{
+			name: "none",
+		},
+		{
+			name:  "hot restart",
+			env:   map[string]string{inheritFDsEnv: "2"},
+			count: 2,
+		},
+		{
+			name: "invalid hot restart",
+			env:  map[string]string{inheritFDsEnv: "-1"},
+			err:  `BEANBRIDGE_INHERIT_FDS="-1"`,
+		},
+		{
+			name: "systemd",
+			env: map[string]string{
+				"LISTEN_PID":     pid,
+				"LISTEN_FDS":     "2",
+				"LISTEN_FDNAMES": "beanstalk:admin",
+			},
+			count: 2,
+			names: []string{"beanstalk", "admin"},
+		},
+		{
+			name: "systemd without names",
+			env: map[string]string{
+				"LISTEN_PID": pid,
+				"LISTEN_FDS": "1",
+			},
+			count: 1,
+		},
+		{
+			name: "other process",
+			env: map[string]string{
+				"LISTEN_PID": "1",
+				"LISTEN_FDS": "2",
+			},
+		},
+		{
+			name: "invalid pid",
+			env: map[string]string{
+				"LISTEN_PID": "abc",
+				"LISTEN_FDS": "2",
+			},
+		},
+		{
+			name: "invalid count",
+			env: map[string]string{
+				"LISTEN_PID": pid,
+				"LISTEN_FDS": "x",
+			},
+			err: `LISTEN_FDS="x"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{inheritFDsEnv, "LISTEN_PID", "LISTEN_FDS", "LISTEN_FDNAMES"} {
+				// Registers the variable to be restored once the test ends
+				t.Setenv(key, "")
+				require.NoError(t, os.Unsetenv(key))
+			}
+
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			count, names, err := inheritedFDs()
+
+			if tt.err != "" {
+				require.ErrorIs(t, err, errInvalidActivation)
+				require.ErrorContains(t, err, tt.err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.count, count)
+				require.Equal(t, tt.names, names)
+			}
+
+			for key := range tt.env {
+				_, ok := os.LookupEnv(key)
+				require.False(t, ok, "%s should be cleared", key)
+			}
+		})
+	}
+}
+
+func TestReadiness(t *testing.T) {
+	r, w, err := os.Pipe()
+	require.NoError(t, err, "Pipe should be created")
+
+	defer r.Close()
+
+	// notifyReady takes ownership of the descriptor it is given
+	fd, err := syscall.Dup(int(w.Fd()))
+	require.NoError(t, err, "Dup should not error")
+	require.NoError(t, w.Close())
+
+	t.Setenv(readyFDEnv, strconv.Itoa(fd))
+
+	require.NoError(t, notifyReady(), "Notify should not error")
+	require.NoError(t, waitReady(r, time.Second), "Parent should see the notification")
+
+	_, ok := os.LookupEnv(readyFDEnv)
+	require.False(t, ok, "Variable should be cleared")
+	require.NoError(t, notifyReady(), "Notify without a parent should do nothing")
+
+	r, w, err = os.Pipe()
+	require.NoError(t, err, "Pipe should be created")
+
+	defer r.Close()
+
+	require.ErrorIs(t, waitReady(r, 10*time.Millisecond), os.ErrDeadlineExceeded)
+
+	require.NoError(t, w.Close())
+	require.ErrorIs(t, waitReady(r, time.Second), errNotReady, "Closed pipe should not count as ready")
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/csnewman/beanbridge/bridge"
+	"gopkg.in/yaml.v3"
+)
+
+const envPrefix = "BEANBRIDGE_"
+
+func loadConfig(path string) (*bridge.Config, error) {
+	cfg := &bridge.Config{}
+
+	if path != "" {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config: %w", err)
+		}
+
+		dec := yaml.NewDecoder(bytes.NewReader(raw))
+		dec.KnownFields(true)
+
+		if err := dec.Decode(cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+		}
+	}
+
+	if _, err := applyEnv(reflect.ValueOf(cfg).Elem(), envPrefix); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// applyEnv overrides fields of v from environment variables, named after the yaml key of each
+// field in upper case, e.g. "backend-config" becomes BEANBRIDGE_BACKEND_CONFIG. Nested structs
+// extend the prefix with their own key. Fields that have no natural string form, such as lists
+// of structs, are parsed as YAML.
+func applyEnv(v reflect.Value, prefix string) (bool, error) {
+	t := v.Type()
+	changed := false
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+
+		if !field.IsExported() {
+			continue
+		}
+
+		key, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if key == "-" {
+			continue
+		}
+
+		if key == "" {
+			key = strings.ToLower(field.Name)
+		}
+
+		name := prefix + strings.ToUpper(strings.ReplaceAll(key, "-", "_"))
+		fv := v.Field(i)
+
+		if isNestedStruct(fv.Type()) {
+			if fv.Kind() == reflect.Struct {
+				nested, err := applyEnv(fv, name+"_")
+				if err != nil {
+					return false, err
+				}
+
+				changed = changed || nested
+
+				continue
+			}
+
+			tmp := reflect.New(fv.Type().Elem())
+			if !fv.IsNil() {
+				tmp.Elem().Set(fv.Elem())
+			}
+
+			nested, err := applyEnv(tmp.Elem(), name+"_")
+			if err != nil {
+				return false, err
+			}
+
+			if nested {
+				fv.Set(tmp)
+
+				changed = true
+			}
+
+			continue
+		}
+
+		raw, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+
+		if err := setFromEnv(fv, raw); err != nil {
+			return false, fmt.Errorf("invalid value for %s: %w", name, err)
+		}
+
+		changed = true
+	}
+
+	return changed, nil
+}
+
+func isNestedStruct(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	return t.Kind() == reflect.Struct && t != reflect.TypeFor[yaml.Node]() && t != reflect.TypeFor[time.Time]()
+}
+
+func setFromEnv(v reflect.Value, raw string) error {
+	switch v.Interface().(type) {
+	case yaml.Node:
+		var node yaml.Node
+
+		if err := yaml.Unmarshal([]byte(raw), &node); err != nil {
+			return err
+		}
+
+		if len(node.Content) > 0 {
+			v.Set(reflect.ValueOf(*node.Content[0]))
+		}
+
+		return nil
+	case time.Duration:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+
+		v.SetInt(int64(d))
+
+		return nil
+	}
+
+	// Types that decode themselves, such as octal file modes, are parsed as in the config file
+	if u, ok := v.Addr().Interface().(yaml.Unmarshaler); ok {
+		return yaml.Unmarshal([]byte(raw), u)
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		v.SetUint(n)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return yaml.Unmarshal([]byte(raw), v.Addr().Interface())
+		}
+
+		var items []string
+
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+
+		v.Set(reflect.ValueOf(items))
+	case reflect.Pointer:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+
+		return setFromEnv(v.Elem(), raw)
+	default:
+		return yaml.Unmarshal([]byte(raw), v.Addr().Interface())
+	}
+
+	return nil
+}
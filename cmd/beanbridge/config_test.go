@@ -0,0 +1,186 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/csnewman/beanbridge/bridge"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+type envNested struct {
+	Port uint16 `yaml:"port"`
+}
+
+type envConfig struct {
+	Name     string        `yaml:"name"`
+	Enabled  bool          `yaml:"enabled"`
+	Count    int           `yaml:"count"`
+	Timeout  time.Duration `yaml:"timeout"`
+	Tags     []string      `yaml:"tags"`
+	Items    []envNested   `yaml:"items"`
+	Raw      yaml.Node     `yaml:"raw-config"`
+	Ptr      *string       `yaml:"ptr"`
+	Nested   envNested     `yaml:"nested"`
+	Optional *envNested    `yaml:"optional"`
+	Skipped  string        `yaml:"-"`
+	Untagged string
+	Mode     bridge.FileMode `yaml:"mode"`
+}
+
+func TestApplyEnv(t *testing.T) {
+	ptr := "value"
+
+	tests := []struct {
+		name     string
+		env      map[string]string
+		expected envConfig
+		err      string
+	}{
+		{
+			name:     "string",
+			env:      map[string]string{"TEST_NAME": "example"},
+			expected: envConfig{Name: "example"},
+		},
+		{
+			name:     "bool and int",
+			env:      map[string]string{"TEST_ENABLED": "true", "TEST_COUNT": "0x10"},
+			expected: envConfig{Enabled: true, Count: 16},
+		},
+		{
+			name:     "duration",
+			env:      map[string]string{"TEST_TIMEOUT": "1m30s"},
+			expected: envConfig{Timeout: 90 * time.Second},
+		},
+		{
+			name:     "string list",
+			env:      map[string]string{"TEST_TAGS": "a, b,,c"},
+			expected: envConfig{Tags: []string{"a", "b", "c"}},
+		},
+		{
+			name:     "struct list as yaml",
+			env:      map[string]string{"TEST_ITEMS": "[{port: 1}, {port: 2}]"},
+			expected: envConfig{Items: []envNested{{Port: 1}, {Port: 2}}},
+		},
+		{
+			name:     "pointer",
+			env:      map[string]string{"TEST_PTR": "value"},
+			expected: envConfig{Ptr: &ptr},
+		},
+		{
+			name:     "nested struct",
+			env:      map[string]string{"TEST_NESTED_PORT": "11300"},
+			expected: envConfig{Nested: envNested{Port: 11300}},
+		},
+		{
+			name:     "nested pointer created when set",
+			env:      map[string]string{"TEST_OPTIONAL_PORT": "80"},
+			expected: envConfig{Optional: &envNested{Port: 80}},
+		},
+		{
+			name:     "untagged and skipped fields",
+			env:      map[string]string{"TEST_UNTAGGED": "yes", "TEST_SKIPPED": "no"},
+			expected: envConfig{Untagged: "yes"},
+		},
+		{
+			name:     "file mode as octal",
+			env:      map[string]string{"TEST_MODE": "660"},
+			expected: envConfig{Mode: 0o660},
+		},
+		{
+			name:     "file mode with prefix",
+			env:      map[string]string{"TEST_MODE": "0o600"},
+			expected: envConfig{Mode: 0o600},
+		},
+		{
+			name: "invalid file mode",
+			env:  map[string]string{"TEST_MODE": "rw"},
+			err:  "invalid value for TEST_MODE",
+		},
+		{
+			name: "invalid int",
+			env:  map[string]string{"TEST_COUNT": "many"},
+			err:  "invalid value for TEST_COUNT",
+		},
+		{
+			name: "out of range",
+			env:  map[string]string{"TEST_NESTED_PORT": "70000"},
+			err:  "invalid value for TEST_NESTED_PORT",
+		},
+		{
+			name: "invalid duration",
+			env:  map[string]string{"TEST_TIMEOUT": "soon"},
+			err:  "invalid value for TEST_TIMEOUT",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			var cfg envConfig
+
+			changed, err := applyEnv(reflect.ValueOf(&cfg).Elem(), "TEST_")
+			if tt.err != "" {
+				require.ErrorContains(t, err, tt.err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			require.True(t, changed, "Config should be changed")
+			require.Equal(t, tt.expected, cfg)
+		})
+	}
+
+	t.Run("yaml node", func(t *testing.T) {
+		t.Setenv("TEST_RAW_CONFIG", "{poll-interval: 1s}")
+
+		var cfg envConfig
+
+		_, err := applyEnv(reflect.ValueOf(&cfg).Elem(), "TEST_")
+		require.NoError(t, err)
+
+		var decoded map[string]string
+		require.NoError(t, cfg.Raw.Decode(&decoded))
+		require.Equal(t, map[string]string{"poll-interval": "1s"}, decoded)
+	})
+
+	t.Run("unset", func(t *testing.T) {
+		var cfg envConfig
+
+		changed, err := applyEnv(reflect.ValueOf(&cfg).Elem(), "TEST_UNSET_")
+		require.NoError(t, err)
+		require.False(t, changed, "Config should be unchanged")
+		require.Nil(t, cfg.Optional, "Nested pointers should not be created")
+	})
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "beanbridge.yaml")
+
+	require.NoError(t, os.WriteFile(path, []byte("address: \":11300\"\nbackend: memory\nmax-job-size: 100\n"), 0o600))
+
+	t.Setenv(envPrefix+"MAX_JOB_SIZE", "200")
+	t.Setenv(envPrefix+"BACKEND", "nullsink")
+
+	opts, err := parseFlags("", []string{"--config", path, "--backend", "memory"})
+	require.NoError(t, err)
+
+	cfg, err := opts.loadConfig()
+	require.NoError(t, err)
+	require.Equal(t, ":11300", cfg.Address, "File values should be kept")
+	require.Equal(t, 200, cfg.MaxJobSize, "Environment should override the file")
+	require.Equal(t, "memory", cfg.Backend, "Flags should override the environment")
+
+	require.NoError(t, os.WriteFile(path, []byte("adress: \":11300\"\n"), 0o600))
+
+	_, err = loadConfig(path)
+	require.ErrorContains(t, err, "field adress not found", "Unknown fields should be rejected")
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/csnewman/beanbridge/bridge"
+)
+
+const healthcheckTimeout = 5 * time.Second
+
+var errHealthNotConfigured = errors.New("health endpoint is not configured")
+
+// healthcheck queries the liveness endpoint of a running instance, for use as a container
+// HEALTHCHECK. Readiness is left to load balancers, so that a draining or degraded instance is not
+// restarted.
+func healthcheck(cfg *bridge.Config) error {
+	if cfg.Health == nil {
+		return errHealthNotConfigured
+	}
+
+	host, port, err := net.SplitHostPort(cfg.Health.Address)
+	if err != nil {
+		return fmt.Errorf("invalid health address: %w", err)
+	}
+
+	// Probe wildcard listeners over IPv4 loopback, as localhost may resolve to an address the
+	// listener is not bound to
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "127.0.0.1"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), healthcheckTimeout)
+	defer cancel()
+
+	url := "http://" + net.JoinHostPort(host, port) + "/healthz"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("health check failed: %w", err)
+	}
+
+	defer res.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unhealthy: %s", strings.TrimSpace(string(body)))
+	}
+
+	fmt.Println("Healthy")
+
+	return nil
+}
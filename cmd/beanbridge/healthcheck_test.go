@@ -0,0 +1,43 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/csnewman/beanbridge/bridge"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthcheck(t *testing.T) {
+	t.Parallel()
+
+	var stopped atomic.Bool
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		if stopped.Load() {
+			http.Error(w, "stopped", http.StatusServiceUnavailable)
+		}
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "draining", http.StatusServiceUnavailable)
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	_, port, err := net.SplitHostPort(srv.Listener.Addr().String())
+	require.NoError(t, err)
+
+	cfg := &bridge.Config{Health: &bridge.HealthConfig{Address: ":" + port}}
+
+	require.NoError(t, healthcheck(cfg), "Wildcard address should be probed on loopback, ignoring readiness")
+
+	stopped.Store(true)
+
+	require.ErrorContains(t, healthcheck(cfg), "unhealthy: stopped")
+	require.ErrorIs(t, healthcheck(&bridge.Config{}), errHealthNotConfigured)
+}
@@ -1,37 +1,297 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
-	"github.com/csnewman/beanbridge/internal/bridge"
-	"gopkg.in/yaml.v3"
+	"github.com/csnewman/beanbridge/bridge"
 )
 
+const (
+	defaultConfigPath = "beanbridge.yaml"
+	cmdValidateConfig = "validate-config"
+	cmdHealthcheck    = "healthcheck"
+)
+
+var errInvalidLogFormat = errors.New("invalid log format")
+
+type options struct {
+	configPath string
+	listen     string
+	backend    string
+	logLevel   slog.Level
+	logFormat  string
+	// explicit holds the log flags set on the command line or by the environment, which take
+	// precedence over the config file
+	explicit map[string]bool
+}
+
 func main() {
+	if err := run(os.Args[1:]); err != nil {
+		// Usage has already been printed, and asking for it is not an error
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
 
-	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
-		AddSource: true,
-		Level:     slog.LevelDebug,
-	}))
+		fmt.Fprintln(os.Stderr, "beanbridge:", err)
+		os.Exit(1)
+	}
+}
 
-	var cfg *bridge.Config
+func run(args []string) error {
+	cmd := ""
 
-	rawCfg, err := os.ReadFile("example.yaml")
+	if len(args) > 0 && (args[0] == cmdValidateConfig || args[0] == cmdHealthcheck) {
+		cmd = args[0]
+		args = args[1:]
+	}
+
+	opts, err := parseFlags(cmd, args)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := opts.loadConfig()
 	if err != nil {
-		panic(err)
+		return err
 	}
 
-	if err := yaml.Unmarshal(rawCfg, &cfg); err != nil {
-		panic(err)
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid config:\n%w", err)
 	}
 
-	s, err := bridge.NewServer(logger, cfg)
+	if cmd == cmdValidateConfig {
+		fmt.Println("Config is valid")
+
+		return nil
+	}
+
+	if cmd == cmdHealthcheck {
+		return healthcheck(cfg)
+	}
+
+	logger := opts.newLogger(os.Stdout, cfg.Log)
+
+	inherited, err := inheritedListeners()
+	if err != nil {
+		return err
+	}
+
+	if len(inherited) > 0 {
+		logger.Info("Using inherited listeners", "count", len(inherited))
+	}
+
+	s, err := bridge.NewServerFromConfig(
+		logger,
+		cfg,
+		bridge.WithInheritedListeners(inherited...),
+	)
 	if err != nil {
-		panic(err)
+		for _, l := range inherited {
+			_ = l.Close()
+		}
+
+		return err
 	}
 
-	if err := s.Serve(); err != nil {
-		panic(err)
+	return serve(logger, s, cfg.GetShutdownTimeout())
+}
+
+// serve runs the server until it fails or a termination signal is received, after which it is
+// gracefully shut down. A second signal aborts the graceful shutdown. Drain signals toggle drain
+// mode whilst serving, and restart signals hand the listeners over to a new process before
+// shutting down.
+func serve(logger *slog.Logger, s *bridge.Server, shutdownTimeout time.Duration) error {
+	sigCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errCh := make(chan error, 1)
+
+	go func() {
+		errCh <- s.Serve()
+	}()
+
+	if err := notifyReady(); err != nil {
+		logger.Warn("Failed to notify parent process", "err", err)
+	}
+
+	drainCh := notify(drainSignals)
+	defer signal.Stop(drainCh)
+
+	restartCh := notify(restartSignals)
+	defer signal.Stop(restartCh)
+
+loop:
+	for {
+		select {
+		case err := <-errCh:
+			return errors.Join(err, s.Close())
+		case <-drainCh:
+			s.SetDraining(!s.Draining())
+		case <-restartCh:
+			pid, err := startReplacement(s)
+			if err != nil {
+				logger.Error("Hot restart failed", "err", err)
+
+				continue
+			}
+
+			logger.Info("Started replacement process", "pid", pid)
+
+			break loop
+		case <-sigCtx.Done():
+			break loop
+		}
+	}
+
+	stop()
+
+	logger.Info("Shutting down", "timeout", shutdownTimeout)
+
+	abortCtx, abort := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer abort()
+
+	ctx, cancel := context.WithTimeout(abortCtx, shutdownTimeout)
+	defer cancel()
+
+	if err := s.Shutdown(ctx); err != nil {
+		return fmt.Errorf("shutdown failed: %w", err)
+	}
+
+	if err := <-errCh; err != nil {
+		return err
+	}
+
+	logger.Info("Shutdown complete")
+
+	return nil
+}
+
+func parseFlags(cmd string, args []string) (*options, error) {
+	name := "beanbridge"
+	if cmd != "" {
+		name += " " + cmd
 	}
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: beanbridge [%s|%s] [flags]\n\nFlags:\n", cmdValidateConfig, cmdHealthcheck)
+		fs.PrintDefaults()
+	}
+
+	opts := &options{
+		explicit: make(map[string]bool),
+	}
+
+	fs.StringVar(&opts.configPath, "config", envOr("CONFIG", defaultConfigPath), "path to the config file")
+	fs.StringVar(&opts.listen, "listen", "", "address to listen on, overrides the config file")
+	fs.StringVar(&opts.backend, "backend", "", "backend to use, overrides the config file")
+	fs.TextVar(&opts.logLevel, "log-level", slog.LevelInfo, "log level (debug, info, warn, error)")
+	fs.StringVar(&opts.logFormat, "log-format", envOr("LOG_FORMAT", "text"), "log format (text, json)")
+
+	if raw, ok := os.LookupEnv(envPrefix + "LOG_LEVEL"); ok {
+		if err := opts.logLevel.UnmarshalText([]byte(raw)); err != nil {
+			return nil, fmt.Errorf("invalid value for %sLOG_LEVEL: %w", envPrefix, err)
+		}
+
+		opts.explicit["log-level"] = true
+	}
+
+	if _, ok := os.LookupEnv(envPrefix + "LOG_FORMAT"); ok {
+		opts.explicit["log-format"] = true
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		opts.explicit[f.Name] = true
+	})
+
+	if fs.NArg() > 0 {
+		fs.Usage()
+
+		return nil, fmt.Errorf("unexpected argument: %s", fs.Arg(0))
+	}
+
+	if opts.logFormat != "text" && opts.logFormat != "json" {
+		return nil, fmt.Errorf("%w: %q, expected text or json", errInvalidLogFormat, opts.logFormat)
+	}
+
+	return opts, nil
+}
+
+func notify(signals []os.Signal) chan os.Signal {
+	ch := make(chan os.Signal, 1)
+
+	if len(signals) > 0 {
+		signal.Notify(ch, signals...)
+	}
+
+	return ch
+}
+
+func envOr(name string, fallback string) string {
+	if v, ok := os.LookupEnv(envPrefix + name); ok {
+		return v
+	}
+
+	return fallback
+}
+
+func (o *options) loadConfig() (*bridge.Config, error) {
+	cfg, err := loadConfig(o.configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if o.listen != "" {
+		cfg.Address = o.listen
+	}
+
+	if o.backend != "" {
+		cfg.Backend = o.backend
+	}
+
+	return cfg, nil
+}
+
+// newLogger creates the logger described by cfg, if any, overridden by explicitly set flags.
+func (o *options) newLogger(w io.Writer, cfg *bridge.LogConfig) *slog.Logger {
+	level := o.logLevel
+	format := o.logFormat
+	source := false
+
+	if cfg != nil {
+		if cfg.Level != "" && !o.explicit["log-level"] {
+			// Already validated
+			level, _ = cfg.ParseLevel()
+		}
+
+		if cfg.Format != "" && !o.explicit["log-format"] {
+			format = cfg.Format
+		}
+
+		source = cfg.Source
+	}
+
+	handlerOpts := &slog.HandlerOptions{
+		AddSource: source,
+		Level:     level,
+	}
+
+	if format == "json" {
+		return slog.New(slog.NewJSONHandler(w, handlerOpts))
+	}
+
+	return slog.New(slog.NewTextHandler(w, handlerOpts))
 }
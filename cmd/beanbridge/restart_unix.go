@@ -0,0 +1,128 @@
+//go:build unix
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/csnewman/beanbridge/bridge"
+)
+
+const (
+	// readyFDEnv holds the file descriptor a replacement process writes to once it is serving.
+	readyFDEnv = envPrefix + "READY_FD"
+	// readyTimeout bounds how long to wait for a replacement process to start serving.
+	readyTimeout = time.Minute
+)
+
+var errNotReady = errors.New("replacement exited before it was ready")
+
+// startReplacement starts a new instance of the running binary, with the same arguments, passing
+// it the listener sockets of s. It returns once the replacement is serving, killing it if it does
+// not become ready in time. Unix sockets are only handed over once the replacement is serving, so
+// that they are still removed on exit if the restart fails.
+func startReplacement(s *bridge.Server) (int, error) {
+	files, err := s.ListenerFiles()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get listener files: %w", err)
+	}
+
+	defer func() {
+		for _, f := range files {
+			_ = f.Close()
+		}
+	}()
+
+	exe, err := os.Executable()
+	if err != nil {
+		return 0, fmt.Errorf("failed to find executable: %w", err)
+	}
+
+	ready, readyW, err := os.Pipe()
+	if err != nil {
+		return 0, fmt.Errorf("failed to create readiness pipe: %w", err)
+	}
+
+	defer ready.Close()
+
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = append(files, readyW)
+	cmd.Env = append(
+		os.Environ(),
+		inheritFDsEnv+"="+strconv.Itoa(len(files)),
+		readyFDEnv+"="+strconv.Itoa(listenFDsStart+len(files)),
+	)
+
+	err = cmd.Start()
+
+	// Only the replacement may hold the write end, so that the pipe closes if it exits
+	_ = readyW.Close()
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to start replacement: %w", err)
+	}
+
+	pid := cmd.Process.Pid
+
+	if err := waitReady(ready, readyTimeout); err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+
+		return 0, fmt.Errorf("replacement %d failed: %w", pid, err)
+	}
+
+	s.HandOver()
+
+	// The replacement outlives this process, so it is never waited on
+	_ = cmd.Process.Release()
+
+	return pid, nil
+}
+
+// waitReady waits for a replacement process to write to the readiness pipe.
+func waitReady(r *os.File, timeout time.Duration) error {
+	if err := r.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return err
+	}
+
+	_, err := r.Read(make([]byte, 1))
+	if errors.Is(err, io.EOF) {
+		return errNotReady
+	}
+
+	return err
+}
+
+// notifyReady tells the parent process performing a hot restart, if any, that this process is
+// serving and the parent can shut down.
+func notifyReady() error {
+	raw, ok := os.LookupEnv(readyFDEnv)
+	if !ok {
+		return nil
+	}
+
+	_ = os.Unsetenv(readyFDEnv)
+
+	fd, err := strconv.Atoi(raw)
+	if err != nil || fd < listenFDsStart {
+		return fmt.Errorf("%w: %s=%q", errInvalidActivation, readyFDEnv, raw)
+	}
+
+	f := os.NewFile(uintptr(fd), "ready")
+	defer f.Close()
+
+	if _, err := f.Write([]byte{1}); err != nil {
+		return fmt.Errorf("failed to notify parent: %w", err)
+	}
+
+	return nil
+}
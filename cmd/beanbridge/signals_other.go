@@ -0,0 +1,10 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+var (
+	drainSignals   []os.Signal
+	restartSignals []os.Signal
+)
@@ -0,0 +1,15 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// drainSignals toggle drain mode, matching the SIGUSR1 behaviour of beanstalkd.
+var drainSignals = []os.Signal{syscall.SIGUSR1}
+
+// restartSignals start a replacement process, which takes over the listening sockets, after which
+// this process gracefully shuts down.
+var restartSignals = []os.Signal{syscall.SIGUSR2}
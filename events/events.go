@@ -0,0 +1,170 @@
+// Package events distributes job state transitions published by a backend to subscribers.
+package events
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/csnewman/beanbridge/acl"
+)
+
+type Type string
+
+const (
+	TypePut      Type = "put"
+	TypeReserved Type = "reserved"
+	TypeReleased Type = "released"
+	TypeBuried   Type = "buried"
+	TypeKicked   Type = "kicked"
+	TypeDeleted  Type = "deleted"
+	// TypeReady is published when the delay of a job elapses.
+	TypeReady Type = "ready"
+	// TypeTimeout is published when a reservation expires, returning the job to the ready queue.
+	TypeTimeout Type = "timeout"
+)
+
+// Types lists all event types.
+var Types = []Type{
+	TypePut,
+	TypeReserved,
+	TypeReleased,
+	TypeBuried,
+	TypeKicked,
+	TypeDeleted,
+	TypeReady,
+	TypeTimeout,
+}
+
+var ErrUnknownType = errors.New("unknown event type")
+
+// ParseTypes converts names into event types, failing on unknown names.
+func ParseTypes(names []string) ([]Type, error) {
+	types := make([]Type, 0, len(names))
+
+	for _, n := range names {
+		if !slices.Contains(Types, Type(n)) {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownType, n)
+		}
+
+		types = append(types, Type(n))
+	}
+
+	return types, nil
+}
+
+type Event struct {
+	Type     Type      `json:"type"`
+	Time     time.Time `json:"time"`
+	JobID    uint64    `json:"job_id"`
+	Tube     string    `json:"tube"`
+	Priority uint64    `json:"priority"`
+}
+
+// Filter selects events by tube, using glob patterns as in acl.Match, and by type. Empty
+// conditions match all events.
+type Filter struct {
+	Tubes []string
+	Types []Type
+}
+
+func (f *Filter) matches(e *Event) bool {
+	if len(f.Types) > 0 && !slices.Contains(f.Types, e.Type) {
+		return false
+	}
+
+	if len(f.Tubes) == 0 {
+		return true
+	}
+
+	for _, t := range f.Tubes {
+		if acl.Match(t, e.Tube) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Bus delivers published events to each matching subscription. Publishing never blocks, events
+// are dropped for subscribers that fall behind.
+type Bus struct {
+	mu   sync.RWMutex
+	subs map[*Subscription]struct{}
+}
+
+func NewBus() *Bus {
+	return &Bus{
+		subs: make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish delivers e to the matching subscriptions. It is safe to call while holding locks.
+func (b *Bus) Publish(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for s := range b.subs {
+		if !s.filter.matches(&e) {
+			continue
+		}
+
+		select {
+		case s.c <- e:
+		default:
+			s.dropped.Add(1)
+		}
+	}
+}
+
+// Subscribe creates a subscription buffering up to size events. It must be closed once no longer
+// needed.
+func (b *Bus) Subscribe(filter Filter, size int) *Subscription {
+	s := &Subscription{
+		bus:    b,
+		filter: filter,
+		c:      make(chan Event, size),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.subs[s] = struct{}{}
+
+	return s
+}
+
+type Subscription struct {
+	bus     *Bus
+	filter  Filter
+	c       chan Event
+	dropped atomic.Uint64
+	closed  sync.Once
+}
+
+// Events returns the channel of events, which is closed once the subscription is closed.
+func (s *Subscription) Events() <-chan Event {
+	return s.c
+}
+
+// Dropped returns the number of events dropped as the subscriber fell behind.
+func (s *Subscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+func (s *Subscription) Close() {
+	s.closed.Do(func() {
+		s.bus.mu.Lock()
+		delete(s.bus.subs, s)
+		s.bus.mu.Unlock()
+
+		close(s.c)
+	})
+}
@@ -0,0 +1,40 @@
+package events_test
+
+import (
+	"testing"
+
+	"github.com/csnewman/beanbridge/events"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBus(t *testing.T) {
+	t.Parallel()
+
+	bus := events.NewBus()
+
+	sub := bus.Subscribe(events.Filter{
+		Tubes: []string{"emails-*"},
+		Types: []events.Type{events.TypeBuried},
+	}, 1)
+
+	bus.Publish(events.Event{Type: events.TypeBuried, JobID: 1, Tube: "emails-low"})
+	bus.Publish(events.Event{Type: events.TypeBuried, JobID: 2, Tube: "emails-high"})
+	bus.Publish(events.Event{Type: events.TypePut, JobID: 3, Tube: "emails-low"})
+	bus.Publish(events.Event{Type: events.TypeBuried, JobID: 4, Tube: "reports"})
+
+	e := <-sub.Events()
+	require.Equal(t, uint64(1), e.JobID)
+	require.False(t, e.Time.IsZero(), "Time should be set on publish")
+	require.Equal(t, uint64(1), sub.Dropped(), "Event should be dropped once the buffer is full")
+
+	sub.Close()
+	sub.Close()
+
+	_, ok := <-sub.Events()
+	require.False(t, ok, "Channel should be closed")
+
+	bus.Publish(events.Event{Type: events.TypeBuried, JobID: 5, Tube: "emails-low"})
+
+	_, err := events.ParseTypes([]string{"put", "lost"})
+	require.ErrorIs(t, err, events.ErrUnknownType)
+}
@@ -1,8 +1,12 @@
-// Code generated by mockery v2.49.1. DO NOT EDIT.
+// Code generated by mockery v2.53.7. DO NOT EDIT.
 
 package mocks
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+)
 
 // MockBeanstalkHandler is an autogenerated mock type for the Handler type
 type MockBeanstalkHandler struct {
@@ -17,17 +21,17 @@ func (_m *MockBeanstalkHandler) EXPECT() *MockBeanstalkHandler_Expecter {
 	return &MockBeanstalkHandler_Expecter{mock: &_m.Mock}
 }
 
-// Bury provides a mock function with given fields: id, pri
-func (_m *MockBeanstalkHandler) Bury(id uint64, pri uint64) error {
-	ret := _m.Called(id, pri)
+// Bury provides a mock function with given fields: ctx, id, pri
+func (_m *MockBeanstalkHandler) Bury(ctx context.Context, id uint64, pri uint64) error {
+	ret := _m.Called(ctx, id, pri)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Bury")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(uint64, uint64) error); ok {
-		r0 = rf(id, pri)
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, uint64) error); ok {
+		r0 = rf(ctx, id, pri)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -41,15 +45,16 @@ type MockBeanstalkHandler_Bury_Call struct {
 }
 
 // Bury is a helper method to define mock.On call
+//   - ctx context.Context
 //   - id uint64
 //   - pri uint64
-func (_e *MockBeanstalkHandler_Expecter) Bury(id interface{}, pri interface{}) *MockBeanstalkHandler_Bury_Call {
-	return &MockBeanstalkHandler_Bury_Call{Call: _e.mock.On("Bury", id, pri)}
+func (_e *MockBeanstalkHandler_Expecter) Bury(ctx interface{}, id interface{}, pri interface{}) *MockBeanstalkHandler_Bury_Call {
+	return &MockBeanstalkHandler_Bury_Call{Call: _e.mock.On("Bury", ctx, id, pri)}
 }
 
-func (_c *MockBeanstalkHandler_Bury_Call) Run(run func(id uint64, pri uint64)) *MockBeanstalkHandler_Bury_Call {
+func (_c *MockBeanstalkHandler_Bury_Call) Run(run func(ctx context.Context, id uint64, pri uint64)) *MockBeanstalkHandler_Bury_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(uint64), args[1].(uint64))
+		run(args[0].(context.Context), args[1].(uint64), args[2].(uint64))
 	})
 	return _c
 }
@@ -59,22 +64,22 @@ func (_c *MockBeanstalkHandler_Bury_Call) Return(_a0 error) *MockBeanstalkHandle
 	return _c
 }
 
-func (_c *MockBeanstalkHandler_Bury_Call) RunAndReturn(run func(uint64, uint64) error) *MockBeanstalkHandler_Bury_Call {
+func (_c *MockBeanstalkHandler_Bury_Call) RunAndReturn(run func(context.Context, uint64, uint64) error) *MockBeanstalkHandler_Bury_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// Delete provides a mock function with given fields: id
-func (_m *MockBeanstalkHandler) Delete(id uint64) error {
-	ret := _m.Called(id)
+// Delete provides a mock function with given fields: ctx, id
+func (_m *MockBeanstalkHandler) Delete(ctx context.Context, id uint64) error {
+	ret := _m.Called(ctx, id)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Delete")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(uint64) error); ok {
-		r0 = rf(id)
+	if rf, ok := ret.Get(0).(func(context.Context, uint64) error); ok {
+		r0 = rf(ctx, id)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -88,14 +93,15 @@ type MockBeanstalkHandler_Delete_Call struct {
 }
 
 // Delete is a helper method to define mock.On call
+//   - ctx context.Context
 //   - id uint64
-func (_e *MockBeanstalkHandler_Expecter) Delete(id interface{}) *MockBeanstalkHandler_Delete_Call {
-	return &MockBeanstalkHandler_Delete_Call{Call: _e.mock.On("Delete", id)}
+func (_e *MockBeanstalkHandler_Expecter) Delete(ctx interface{}, id interface{}) *MockBeanstalkHandler_Delete_Call {
+	return &MockBeanstalkHandler_Delete_Call{Call: _e.mock.On("Delete", ctx, id)}
 }
 
-func (_c *MockBeanstalkHandler_Delete_Call) Run(run func(id uint64)) *MockBeanstalkHandler_Delete_Call {
+func (_c *MockBeanstalkHandler_Delete_Call) Run(run func(ctx context.Context, id uint64)) *MockBeanstalkHandler_Delete_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(uint64))
+		run(args[0].(context.Context), args[1].(uint64))
 	})
 	return _c
 }
@@ -105,14 +111,14 @@ func (_c *MockBeanstalkHandler_Delete_Call) Return(_a0 error) *MockBeanstalkHand
 	return _c
 }
 
-func (_c *MockBeanstalkHandler_Delete_Call) RunAndReturn(run func(uint64) error) *MockBeanstalkHandler_Delete_Call {
+func (_c *MockBeanstalkHandler_Delete_Call) RunAndReturn(run func(context.Context, uint64) error) *MockBeanstalkHandler_Delete_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// Ignore provides a mock function with given fields: tube
-func (_m *MockBeanstalkHandler) Ignore(tube string) (int, error) {
-	ret := _m.Called(tube)
+// Ignore provides a mock function with given fields: ctx, tube
+func (_m *MockBeanstalkHandler) Ignore(ctx context.Context, tube string) (int, error) {
+	ret := _m.Called(ctx, tube)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Ignore")
@@ -120,17 +126,17 @@ func (_m *MockBeanstalkHandler) Ignore(tube string) (int, error) {
 
 	var r0 int
 	var r1 error
-	if rf, ok := ret.Get(0).(func(string) (int, error)); ok {
-		return rf(tube)
+	if rf, ok := ret.Get(0).(func(context.Context, string) (int, error)); ok {
+		return rf(ctx, tube)
 	}
-	if rf, ok := ret.Get(0).(func(string) int); ok {
-		r0 = rf(tube)
+	if rf, ok := ret.Get(0).(func(context.Context, string) int); ok {
+		r0 = rf(ctx, tube)
 	} else {
 		r0 = ret.Get(0).(int)
 	}
 
-	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(tube)
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, tube)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -144,14 +150,15 @@ type MockBeanstalkHandler_Ignore_Call struct {
 }
 
 // Ignore is a helper method to define mock.On call
+//   - ctx context.Context
 //   - tube string
-func (_e *MockBeanstalkHandler_Expecter) Ignore(tube interface{}) *MockBeanstalkHandler_Ignore_Call {
-	return &MockBeanstalkHandler_Ignore_Call{Call: _e.mock.On("Ignore", tube)}
+func (_e *MockBeanstalkHandler_Expecter) Ignore(ctx interface{}, tube interface{}) *MockBeanstalkHandler_Ignore_Call {
+	return &MockBeanstalkHandler_Ignore_Call{Call: _e.mock.On("Ignore", ctx, tube)}
 }
 
-func (_c *MockBeanstalkHandler_Ignore_Call) Run(run func(tube string)) *MockBeanstalkHandler_Ignore_Call {
+func (_c *MockBeanstalkHandler_Ignore_Call) Run(run func(ctx context.Context, tube string)) *MockBeanstalkHandler_Ignore_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(string))
+		run(args[0].(context.Context), args[1].(string))
 	})
 	return _c
 }
@@ -161,14 +168,166 @@ func (_c *MockBeanstalkHandler_Ignore_Call) Return(_a0 int, _a1 error) *MockBean
 	return _c
 }
 
-func (_c *MockBeanstalkHandler_Ignore_Call) RunAndReturn(run func(string) (int, error)) *MockBeanstalkHandler_Ignore_Call {
+func (_c *MockBeanstalkHandler_Ignore_Call) RunAndReturn(run func(context.Context, string) (int, error)) *MockBeanstalkHandler_Ignore_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Kick provides a mock function with given fields: ctx, bound
+func (_m *MockBeanstalkHandler) Kick(ctx context.Context, bound uint64) (uint64, error) {
+	ret := _m.Called(ctx, bound)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Kick")
+	}
+
+	var r0 uint64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint64) (uint64, error)); ok {
+		return rf(ctx, bound)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uint64) uint64); ok {
+		r0 = rf(ctx, bound)
+	} else {
+		r0 = ret.Get(0).(uint64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uint64) error); ok {
+		r1 = rf(ctx, bound)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockBeanstalkHandler_Kick_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Kick'
+type MockBeanstalkHandler_Kick_Call struct {
+	*mock.Call
+}
+
+// Kick is a helper method to define mock.On call
+//   - ctx context.Context
+//   - bound uint64
+func (_e *MockBeanstalkHandler_Expecter) Kick(ctx interface{}, bound interface{}) *MockBeanstalkHandler_Kick_Call {
+	return &MockBeanstalkHandler_Kick_Call{Call: _e.mock.On("Kick", ctx, bound)}
+}
+
+func (_c *MockBeanstalkHandler_Kick_Call) Run(run func(ctx context.Context, bound uint64)) *MockBeanstalkHandler_Kick_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(uint64))
+	})
+	return _c
+}
+
+func (_c *MockBeanstalkHandler_Kick_Call) Return(_a0 uint64, _a1 error) *MockBeanstalkHandler_Kick_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockBeanstalkHandler_Kick_Call) RunAndReturn(run func(context.Context, uint64) (uint64, error)) *MockBeanstalkHandler_Kick_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// KickJob provides a mock function with given fields: ctx, id
+func (_m *MockBeanstalkHandler) KickJob(ctx context.Context, id uint64) error {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for KickJob")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint64) error); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockBeanstalkHandler_KickJob_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'KickJob'
+type MockBeanstalkHandler_KickJob_Call struct {
+	*mock.Call
+}
+
+// KickJob is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id uint64
+func (_e *MockBeanstalkHandler_Expecter) KickJob(ctx interface{}, id interface{}) *MockBeanstalkHandler_KickJob_Call {
+	return &MockBeanstalkHandler_KickJob_Call{Call: _e.mock.On("KickJob", ctx, id)}
+}
+
+func (_c *MockBeanstalkHandler_KickJob_Call) Run(run func(ctx context.Context, id uint64)) *MockBeanstalkHandler_KickJob_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(uint64))
+	})
+	return _c
+}
+
+func (_c *MockBeanstalkHandler_KickJob_Call) Return(_a0 error) *MockBeanstalkHandler_KickJob_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockBeanstalkHandler_KickJob_Call) RunAndReturn(run func(context.Context, uint64) error) *MockBeanstalkHandler_KickJob_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// PauseTube provides a mock function with given fields: ctx, tube, delay
+func (_m *MockBeanstalkHandler) PauseTube(ctx context.Context, tube string, delay uint64) error {
+	ret := _m.Called(ctx, tube, delay)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PauseTube")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, uint64) error); ok {
+		r0 = rf(ctx, tube, delay)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockBeanstalkHandler_PauseTube_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'PauseTube'
+type MockBeanstalkHandler_PauseTube_Call struct {
+	*mock.Call
+}
+
+// PauseTube is a helper method to define mock.On call
+//   - ctx context.Context
+//   - tube string
+//   - delay uint64
+func (_e *MockBeanstalkHandler_Expecter) PauseTube(ctx interface{}, tube interface{}, delay interface{}) *MockBeanstalkHandler_PauseTube_Call {
+	return &MockBeanstalkHandler_PauseTube_Call{Call: _e.mock.On("PauseTube", ctx, tube, delay)}
+}
+
+func (_c *MockBeanstalkHandler_PauseTube_Call) Run(run func(ctx context.Context, tube string, delay uint64)) *MockBeanstalkHandler_PauseTube_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(uint64))
+	})
+	return _c
+}
+
+func (_c *MockBeanstalkHandler_PauseTube_Call) Return(_a0 error) *MockBeanstalkHandler_PauseTube_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockBeanstalkHandler_PauseTube_Call) RunAndReturn(run func(context.Context, string, uint64) error) *MockBeanstalkHandler_PauseTube_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// Put provides a mock function with given fields: pri, delay, ttr, data
-func (_m *MockBeanstalkHandler) Put(pri uint64, delay uint64, ttr uint64, data []byte) (uint64, bool, error) {
-	ret := _m.Called(pri, delay, ttr, data)
+// Put provides a mock function with given fields: ctx, pri, delay, ttr, data
+func (_m *MockBeanstalkHandler) Put(ctx context.Context, pri uint64, delay uint64, ttr uint64, data []byte) (uint64, bool, error) {
+	ret := _m.Called(ctx, pri, delay, ttr, data)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Put")
@@ -177,23 +336,23 @@ func (_m *MockBeanstalkHandler) Put(pri uint64, delay uint64, ttr uint64, data [
 	var r0 uint64
 	var r1 bool
 	var r2 error
-	if rf, ok := ret.Get(0).(func(uint64, uint64, uint64, []byte) (uint64, bool, error)); ok {
-		return rf(pri, delay, ttr, data)
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, uint64, uint64, []byte) (uint64, bool, error)); ok {
+		return rf(ctx, pri, delay, ttr, data)
 	}
-	if rf, ok := ret.Get(0).(func(uint64, uint64, uint64, []byte) uint64); ok {
-		r0 = rf(pri, delay, ttr, data)
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, uint64, uint64, []byte) uint64); ok {
+		r0 = rf(ctx, pri, delay, ttr, data)
 	} else {
 		r0 = ret.Get(0).(uint64)
 	}
 
-	if rf, ok := ret.Get(1).(func(uint64, uint64, uint64, []byte) bool); ok {
-		r1 = rf(pri, delay, ttr, data)
+	if rf, ok := ret.Get(1).(func(context.Context, uint64, uint64, uint64, []byte) bool); ok {
+		r1 = rf(ctx, pri, delay, ttr, data)
 	} else {
 		r1 = ret.Get(1).(bool)
 	}
 
-	if rf, ok := ret.Get(2).(func(uint64, uint64, uint64, []byte) error); ok {
-		r2 = rf(pri, delay, ttr, data)
+	if rf, ok := ret.Get(2).(func(context.Context, uint64, uint64, uint64, []byte) error); ok {
+		r2 = rf(ctx, pri, delay, ttr, data)
 	} else {
 		r2 = ret.Error(2)
 	}
@@ -207,17 +366,18 @@ type MockBeanstalkHandler_Put_Call struct {
 }
 
 // Put is a helper method to define mock.On call
+//   - ctx context.Context
 //   - pri uint64
 //   - delay uint64
 //   - ttr uint64
 //   - data []byte
-func (_e *MockBeanstalkHandler_Expecter) Put(pri interface{}, delay interface{}, ttr interface{}, data interface{}) *MockBeanstalkHandler_Put_Call {
-	return &MockBeanstalkHandler_Put_Call{Call: _e.mock.On("Put", pri, delay, ttr, data)}
+func (_e *MockBeanstalkHandler_Expecter) Put(ctx interface{}, pri interface{}, delay interface{}, ttr interface{}, data interface{}) *MockBeanstalkHandler_Put_Call {
+	return &MockBeanstalkHandler_Put_Call{Call: _e.mock.On("Put", ctx, pri, delay, ttr, data)}
 }
 
-func (_c *MockBeanstalkHandler_Put_Call) Run(run func(pri uint64, delay uint64, ttr uint64, data []byte)) *MockBeanstalkHandler_Put_Call {
+func (_c *MockBeanstalkHandler_Put_Call) Run(run func(ctx context.Context, pri uint64, delay uint64, ttr uint64, data []byte)) *MockBeanstalkHandler_Put_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(uint64), args[1].(uint64), args[2].(uint64), args[3].([]byte))
+		run(args[0].(context.Context), args[1].(uint64), args[2].(uint64), args[3].(uint64), args[4].([]byte))
 	})
 	return _c
 }
@@ -227,22 +387,22 @@ func (_c *MockBeanstalkHandler_Put_Call) Return(_a0 uint64, _a1 bool, _a2 error)
 	return _c
 }
 
-func (_c *MockBeanstalkHandler_Put_Call) RunAndReturn(run func(uint64, uint64, uint64, []byte) (uint64, bool, error)) *MockBeanstalkHandler_Put_Call {
+func (_c *MockBeanstalkHandler_Put_Call) RunAndReturn(run func(context.Context, uint64, uint64, uint64, []byte) (uint64, bool, error)) *MockBeanstalkHandler_Put_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// Release provides a mock function with given fields: id, pri, delay
-func (_m *MockBeanstalkHandler) Release(id uint64, pri uint64, delay uint64) error {
-	ret := _m.Called(id, pri, delay)
+// Release provides a mock function with given fields: ctx, id, pri, delay
+func (_m *MockBeanstalkHandler) Release(ctx context.Context, id uint64, pri uint64, delay uint64) error {
+	ret := _m.Called(ctx, id, pri, delay)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Release")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(uint64, uint64, uint64) error); ok {
-		r0 = rf(id, pri, delay)
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, uint64, uint64) error); ok {
+		r0 = rf(ctx, id, pri, delay)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -256,16 +416,17 @@ type MockBeanstalkHandler_Release_Call struct {
 }
 
 // Release is a helper method to define mock.On call
+//   - ctx context.Context
 //   - id uint64
 //   - pri uint64
 //   - delay uint64
-func (_e *MockBeanstalkHandler_Expecter) Release(id interface{}, pri interface{}, delay interface{}) *MockBeanstalkHandler_Release_Call {
-	return &MockBeanstalkHandler_Release_Call{Call: _e.mock.On("Release", id, pri, delay)}
+func (_e *MockBeanstalkHandler_Expecter) Release(ctx interface{}, id interface{}, pri interface{}, delay interface{}) *MockBeanstalkHandler_Release_Call {
+	return &MockBeanstalkHandler_Release_Call{Call: _e.mock.On("Release", ctx, id, pri, delay)}
 }
 
-func (_c *MockBeanstalkHandler_Release_Call) Run(run func(id uint64, pri uint64, delay uint64)) *MockBeanstalkHandler_Release_Call {
+func (_c *MockBeanstalkHandler_Release_Call) Run(run func(ctx context.Context, id uint64, pri uint64, delay uint64)) *MockBeanstalkHandler_Release_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(uint64), args[1].(uint64), args[2].(uint64))
+		run(args[0].(context.Context), args[1].(uint64), args[2].(uint64), args[3].(uint64))
 	})
 	return _c
 }
@@ -275,14 +436,14 @@ func (_c *MockBeanstalkHandler_Release_Call) Return(_a0 error) *MockBeanstalkHan
 	return _c
 }
 
-func (_c *MockBeanstalkHandler_Release_Call) RunAndReturn(run func(uint64, uint64, uint64) error) *MockBeanstalkHandler_Release_Call {
+func (_c *MockBeanstalkHandler_Release_Call) RunAndReturn(run func(context.Context, uint64, uint64, uint64) error) *MockBeanstalkHandler_Release_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// Reserve provides a mock function with given fields: timeout
-func (_m *MockBeanstalkHandler) Reserve(timeout int64) (uint64, []byte, error) {
-	ret := _m.Called(timeout)
+// Reserve provides a mock function with given fields: ctx, timeout
+func (_m *MockBeanstalkHandler) Reserve(ctx context.Context, timeout int64) (uint64, []byte, error) {
+	ret := _m.Called(ctx, timeout)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Reserve")
@@ -291,25 +452,25 @@ func (_m *MockBeanstalkHandler) Reserve(timeout int64) (uint64, []byte, error) {
 	var r0 uint64
 	var r1 []byte
 	var r2 error
-	if rf, ok := ret.Get(0).(func(int64) (uint64, []byte, error)); ok {
-		return rf(timeout)
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (uint64, []byte, error)); ok {
+		return rf(ctx, timeout)
 	}
-	if rf, ok := ret.Get(0).(func(int64) uint64); ok {
-		r0 = rf(timeout)
+	if rf, ok := ret.Get(0).(func(context.Context, int64) uint64); ok {
+		r0 = rf(ctx, timeout)
 	} else {
 		r0 = ret.Get(0).(uint64)
 	}
 
-	if rf, ok := ret.Get(1).(func(int64) []byte); ok {
-		r1 = rf(timeout)
+	if rf, ok := ret.Get(1).(func(context.Context, int64) []byte); ok {
+		r1 = rf(ctx, timeout)
 	} else {
 		if ret.Get(1) != nil {
 			r1 = ret.Get(1).([]byte)
 		}
 	}
 
-	if rf, ok := ret.Get(2).(func(int64) error); ok {
-		r2 = rf(timeout)
+	if rf, ok := ret.Get(2).(func(context.Context, int64) error); ok {
+		r2 = rf(ctx, timeout)
 	} else {
 		r2 = ret.Error(2)
 	}
@@ -323,14 +484,15 @@ type MockBeanstalkHandler_Reserve_Call struct {
 }
 
 // Reserve is a helper method to define mock.On call
+//   - ctx context.Context
 //   - timeout int64
-func (_e *MockBeanstalkHandler_Expecter) Reserve(timeout interface{}) *MockBeanstalkHandler_Reserve_Call {
-	return &MockBeanstalkHandler_Reserve_Call{Call: _e.mock.On("Reserve", timeout)}
+func (_e *MockBeanstalkHandler_Expecter) Reserve(ctx interface{}, timeout interface{}) *MockBeanstalkHandler_Reserve_Call {
+	return &MockBeanstalkHandler_Reserve_Call{Call: _e.mock.On("Reserve", ctx, timeout)}
 }
 
-func (_c *MockBeanstalkHandler_Reserve_Call) Run(run func(timeout int64)) *MockBeanstalkHandler_Reserve_Call {
+func (_c *MockBeanstalkHandler_Reserve_Call) Run(run func(ctx context.Context, timeout int64)) *MockBeanstalkHandler_Reserve_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(int64))
+		run(args[0].(context.Context), args[1].(int64))
 	})
 	return _c
 }
@@ -340,14 +502,14 @@ func (_c *MockBeanstalkHandler_Reserve_Call) Return(_a0 uint64, _a1 []byte, _a2
 	return _c
 }
 
-func (_c *MockBeanstalkHandler_Reserve_Call) RunAndReturn(run func(int64) (uint64, []byte, error)) *MockBeanstalkHandler_Reserve_Call {
+func (_c *MockBeanstalkHandler_Reserve_Call) RunAndReturn(run func(context.Context, int64) (uint64, []byte, error)) *MockBeanstalkHandler_Reserve_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// ReserveByID provides a mock function with given fields: id
-func (_m *MockBeanstalkHandler) ReserveByID(id uint64) (uint64, []byte, error) {
-	ret := _m.Called(id)
+// ReserveByID provides a mock function with given fields: ctx, id
+func (_m *MockBeanstalkHandler) ReserveByID(ctx context.Context, id uint64) (uint64, []byte, error) {
+	ret := _m.Called(ctx, id)
 
 	if len(ret) == 0 {
 		panic("no return value specified for ReserveByID")
@@ -356,25 +518,25 @@ func (_m *MockBeanstalkHandler) ReserveByID(id uint64) (uint64, []byte, error) {
 	var r0 uint64
 	var r1 []byte
 	var r2 error
-	if rf, ok := ret.Get(0).(func(uint64) (uint64, []byte, error)); ok {
-		return rf(id)
+	if rf, ok := ret.Get(0).(func(context.Context, uint64) (uint64, []byte, error)); ok {
+		return rf(ctx, id)
 	}
-	if rf, ok := ret.Get(0).(func(uint64) uint64); ok {
-		r0 = rf(id)
+	if rf, ok := ret.Get(0).(func(context.Context, uint64) uint64); ok {
+		r0 = rf(ctx, id)
 	} else {
 		r0 = ret.Get(0).(uint64)
 	}
 
-	if rf, ok := ret.Get(1).(func(uint64) []byte); ok {
-		r1 = rf(id)
+	if rf, ok := ret.Get(1).(func(context.Context, uint64) []byte); ok {
+		r1 = rf(ctx, id)
 	} else {
 		if ret.Get(1) != nil {
 			r1 = ret.Get(1).([]byte)
 		}
 	}
 
-	if rf, ok := ret.Get(2).(func(uint64) error); ok {
-		r2 = rf(id)
+	if rf, ok := ret.Get(2).(func(context.Context, uint64) error); ok {
+		r2 = rf(ctx, id)
 	} else {
 		r2 = ret.Error(2)
 	}
@@ -388,14 +550,15 @@ type MockBeanstalkHandler_ReserveByID_Call struct {
 }
 
 // ReserveByID is a helper method to define mock.On call
+//   - ctx context.Context
 //   - id uint64
-func (_e *MockBeanstalkHandler_Expecter) ReserveByID(id interface{}) *MockBeanstalkHandler_ReserveByID_Call {
-	return &MockBeanstalkHandler_ReserveByID_Call{Call: _e.mock.On("ReserveByID", id)}
+func (_e *MockBeanstalkHandler_Expecter) ReserveByID(ctx interface{}, id interface{}) *MockBeanstalkHandler_ReserveByID_Call {
+	return &MockBeanstalkHandler_ReserveByID_Call{Call: _e.mock.On("ReserveByID", ctx, id)}
 }
 
-func (_c *MockBeanstalkHandler_ReserveByID_Call) Run(run func(id uint64)) *MockBeanstalkHandler_ReserveByID_Call {
+func (_c *MockBeanstalkHandler_ReserveByID_Call) Run(run func(ctx context.Context, id uint64)) *MockBeanstalkHandler_ReserveByID_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(uint64))
+		run(args[0].(context.Context), args[1].(uint64))
 	})
 	return _c
 }
@@ -405,22 +568,22 @@ func (_c *MockBeanstalkHandler_ReserveByID_Call) Return(_a0 uint64, _a1 []byte,
 	return _c
 }
 
-func (_c *MockBeanstalkHandler_ReserveByID_Call) RunAndReturn(run func(uint64) (uint64, []byte, error)) *MockBeanstalkHandler_ReserveByID_Call {
+func (_c *MockBeanstalkHandler_ReserveByID_Call) RunAndReturn(run func(context.Context, uint64) (uint64, []byte, error)) *MockBeanstalkHandler_ReserveByID_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// Touch provides a mock function with given fields: id
-func (_m *MockBeanstalkHandler) Touch(id uint64) error {
-	ret := _m.Called(id)
+// Touch provides a mock function with given fields: ctx, id
+func (_m *MockBeanstalkHandler) Touch(ctx context.Context, id uint64) error {
+	ret := _m.Called(ctx, id)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Touch")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(uint64) error); ok {
-		r0 = rf(id)
+	if rf, ok := ret.Get(0).(func(context.Context, uint64) error); ok {
+		r0 = rf(ctx, id)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -434,14 +597,15 @@ type MockBeanstalkHandler_Touch_Call struct {
 }
 
 // Touch is a helper method to define mock.On call
+//   - ctx context.Context
 //   - id uint64
-func (_e *MockBeanstalkHandler_Expecter) Touch(id interface{}) *MockBeanstalkHandler_Touch_Call {
-	return &MockBeanstalkHandler_Touch_Call{Call: _e.mock.On("Touch", id)}
+func (_e *MockBeanstalkHandler_Expecter) Touch(ctx interface{}, id interface{}) *MockBeanstalkHandler_Touch_Call {
+	return &MockBeanstalkHandler_Touch_Call{Call: _e.mock.On("Touch", ctx, id)}
 }
 
-func (_c *MockBeanstalkHandler_Touch_Call) Run(run func(id uint64)) *MockBeanstalkHandler_Touch_Call {
+func (_c *MockBeanstalkHandler_Touch_Call) Run(run func(ctx context.Context, id uint64)) *MockBeanstalkHandler_Touch_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(uint64))
+		run(args[0].(context.Context), args[1].(uint64))
 	})
 	return _c
 }
@@ -451,14 +615,14 @@ func (_c *MockBeanstalkHandler_Touch_Call) Return(_a0 error) *MockBeanstalkHandl
 	return _c
 }
 
-func (_c *MockBeanstalkHandler_Touch_Call) RunAndReturn(run func(uint64) error) *MockBeanstalkHandler_Touch_Call {
+func (_c *MockBeanstalkHandler_Touch_Call) RunAndReturn(run func(context.Context, uint64) error) *MockBeanstalkHandler_Touch_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// Use provides a mock function with given fields: tube
-func (_m *MockBeanstalkHandler) Use(tube string) (string, error) {
-	ret := _m.Called(tube)
+// Use provides a mock function with given fields: ctx, tube
+func (_m *MockBeanstalkHandler) Use(ctx context.Context, tube string) (string, error) {
+	ret := _m.Called(ctx, tube)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Use")
@@ -466,17 +630,17 @@ func (_m *MockBeanstalkHandler) Use(tube string) (string, error) {
 
 	var r0 string
 	var r1 error
-	if rf, ok := ret.Get(0).(func(string) (string, error)); ok {
-		return rf(tube)
+	if rf, ok := ret.Get(0).(func(context.Context, string) (string, error)); ok {
+		return rf(ctx, tube)
 	}
-	if rf, ok := ret.Get(0).(func(string) string); ok {
-		r0 = rf(tube)
+	if rf, ok := ret.Get(0).(func(context.Context, string) string); ok {
+		r0 = rf(ctx, tube)
 	} else {
 		r0 = ret.Get(0).(string)
 	}
 
-	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(tube)
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, tube)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -490,14 +654,15 @@ type MockBeanstalkHandler_Use_Call struct {
 }
 
 // Use is a helper method to define mock.On call
+//   - ctx context.Context
 //   - tube string
-func (_e *MockBeanstalkHandler_Expecter) Use(tube interface{}) *MockBeanstalkHandler_Use_Call {
-	return &MockBeanstalkHandler_Use_Call{Call: _e.mock.On("Use", tube)}
+func (_e *MockBeanstalkHandler_Expecter) Use(ctx interface{}, tube interface{}) *MockBeanstalkHandler_Use_Call {
+	return &MockBeanstalkHandler_Use_Call{Call: _e.mock.On("Use", ctx, tube)}
 }
 
-func (_c *MockBeanstalkHandler_Use_Call) Run(run func(tube string)) *MockBeanstalkHandler_Use_Call {
+func (_c *MockBeanstalkHandler_Use_Call) Run(run func(ctx context.Context, tube string)) *MockBeanstalkHandler_Use_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(string))
+		run(args[0].(context.Context), args[1].(string))
 	})
 	return _c
 }
@@ -507,14 +672,14 @@ func (_c *MockBeanstalkHandler_Use_Call) Return(_a0 string, _a1 error) *MockBean
 	return _c
 }
 
-func (_c *MockBeanstalkHandler_Use_Call) RunAndReturn(run func(string) (string, error)) *MockBeanstalkHandler_Use_Call {
+func (_c *MockBeanstalkHandler_Use_Call) RunAndReturn(run func(context.Context, string) (string, error)) *MockBeanstalkHandler_Use_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// Watch provides a mock function with given fields: tube
-func (_m *MockBeanstalkHandler) Watch(tube string) (int, error) {
-	ret := _m.Called(tube)
+// Watch provides a mock function with given fields: ctx, tube
+func (_m *MockBeanstalkHandler) Watch(ctx context.Context, tube string) (int, error) {
+	ret := _m.Called(ctx, tube)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Watch")
@@ -522,17 +687,17 @@ func (_m *MockBeanstalkHandler) Watch(tube string) (int, error) {
 
 	var r0 int
 	var r1 error
-	if rf, ok := ret.Get(0).(func(string) (int, error)); ok {
-		return rf(tube)
+	if rf, ok := ret.Get(0).(func(context.Context, string) (int, error)); ok {
+		return rf(ctx, tube)
 	}
-	if rf, ok := ret.Get(0).(func(string) int); ok {
-		r0 = rf(tube)
+	if rf, ok := ret.Get(0).(func(context.Context, string) int); ok {
+		r0 = rf(ctx, tube)
 	} else {
 		r0 = ret.Get(0).(int)
 	}
 
-	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(tube)
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, tube)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -546,14 +711,15 @@ type MockBeanstalkHandler_Watch_Call struct {
 }
 
 // Watch is a helper method to define mock.On call
+//   - ctx context.Context
 //   - tube string
-func (_e *MockBeanstalkHandler_Expecter) Watch(tube interface{}) *MockBeanstalkHandler_Watch_Call {
-	return &MockBeanstalkHandler_Watch_Call{Call: _e.mock.On("Watch", tube)}
+func (_e *MockBeanstalkHandler_Expecter) Watch(ctx interface{}, tube interface{}) *MockBeanstalkHandler_Watch_Call {
+	return &MockBeanstalkHandler_Watch_Call{Call: _e.mock.On("Watch", ctx, tube)}
 }
 
-func (_c *MockBeanstalkHandler_Watch_Call) Run(run func(tube string)) *MockBeanstalkHandler_Watch_Call {
+func (_c *MockBeanstalkHandler_Watch_Call) Run(run func(ctx context.Context, tube string)) *MockBeanstalkHandler_Watch_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(string))
+		run(args[0].(context.Context), args[1].(string))
 	})
 	return _c
 }
@@ -563,7 +729,7 @@ func (_c *MockBeanstalkHandler_Watch_Call) Return(_a0 int, _a1 error) *MockBeans
 	return _c
 }
 
-func (_c *MockBeanstalkHandler_Watch_Call) RunAndReturn(run func(string) (int, error)) *MockBeanstalkHandler_Watch_Call {
+func (_c *MockBeanstalkHandler_Watch_Call) RunAndReturn(run func(context.Context, string) (int, error)) *MockBeanstalkHandler_Watch_Call {
 	_c.Call.Return(run)
 	return _c
 }
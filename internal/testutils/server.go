@@ -2,15 +2,17 @@ package testutils
 
 import (
 	"context"
-	"github.com/csnewman/beanbridge/internal/beanstalk"
+	"github.com/csnewman/beanbridge/beanstalk"
 	"github.com/neilotoole/slogt"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/sync/errgroup"
 	"testing"
 )
 
-func Server(t *testing.T, factory beanstalk.Factory, f func(t *testing.T, s *beanstalk.Server)) {
-	s, err := beanstalk.NewServer(slogt.New(t), ":", factory)
+func Server(t *testing.T, factory beanstalk.Factory, f func(t *testing.T, s *beanstalk.Server), opts ...beanstalk.Option) {
+	opts = append([]beanstalk.Option{beanstalk.WithLogger(slogt.New(t)), beanstalk.WithAddress(":")}, opts...)
+
+	s, err := beanstalk.NewServer(factory, opts...)
 	require.NoError(t, err, "Server should not error")
 	require.NotNil(t, s, "Server should not be nil")
 
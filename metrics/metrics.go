@@ -0,0 +1,186 @@
+// Package metrics exports bridge, connection and backend statistics to Prometheus.
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/csnewman/beanbridge/backend"
+	"github.com/csnewman/beanbridge/beanstalk"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	namespace      = "beanbridge"
+	collectTimeout = 5 * time.Second
+)
+
+// Metrics records command metrics, implementing beanstalk.Observer.
+type Metrics struct {
+	commands *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+// New creates the command metrics and registers them with reg.
+func New(reg prometheus.Registerer) (*Metrics, error) {
+	m := &Metrics{
+		commands: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "commands_total",
+			Help:      "Number of beanstalk commands processed, by command and response status.",
+		}, []string{"command", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "command_duration_seconds",
+			Help:      "Time taken to process beanstalk commands, including time blocked in reserve.",
+			Buckets:   prometheus.ExponentialBuckets(0.0001, 4, 12),
+		}, []string{"command"}),
+	}
+
+	if err := reg.Register(m.commands); err != nil {
+		return nil, fmt.Errorf("failed to register command metrics: %w", err)
+	}
+
+	if err := reg.Register(m.duration); err != nil {
+		return nil, fmt.Errorf("failed to register command metrics: %w", err)
+	}
+
+	return m, nil
+}
+
+func (m *Metrics) ObserveCommand(cmd string, status string, duration time.Duration) {
+	m.commands.WithLabelValues(cmd, status).Inc()
+	m.duration.WithLabelValues(cmd).Observe(duration.Seconds())
+}
+
+// ServerCollector exports the connection counters of a beanstalk server.
+type ServerCollector struct {
+	stats func() beanstalk.Stats
+
+	active        *prometheus.Desc
+	total         *prometheus.Desc
+	rejected      *prometheus.Desc
+	idleTimeouts  *prometheus.Desc
+	writeTimeouts *prometheus.Desc
+}
+
+// NewServerCollector creates a collector reporting the counters returned by stats, typically
+// beanstalk.Server.Stats.
+func NewServerCollector(stats func() beanstalk.Stats) *ServerCollector {
+	return &ServerCollector{
+		stats: stats,
+		active: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "connections_active"),
+			"Number of open beanstalk connections.",
+			nil, nil,
+		),
+		total: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "connections_total"),
+			"Number of beanstalk connections accepted.",
+			nil, nil,
+		),
+		rejected: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "connections_rejected_total"),
+			"Number of beanstalk connections rejected due to connection limits.",
+			[]string{"reason"}, nil,
+		),
+		idleTimeouts: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "idle_timeouts_total"),
+			"Number of connections closed for being idle.",
+			nil, nil,
+		),
+		writeTimeouts: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "write_timeouts_total"),
+			"Number of connections closed for not reading responses.",
+			nil, nil,
+		),
+	}
+}
+
+func (c *ServerCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.active
+	ch <- c.total
+	ch <- c.rejected
+	ch <- c.idleTimeouts
+	ch <- c.writeTimeouts
+}
+
+func (c *ServerCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.stats()
+
+	ch <- prometheus.MustNewConstMetric(c.active, prometheus.GaugeValue, float64(s.CurrentConnections))
+	ch <- prometheus.MustNewConstMetric(c.total, prometheus.CounterValue, float64(s.TotalConnections))
+	ch <- prometheus.MustNewConstMetric(c.rejected, prometheus.CounterValue, float64(s.RejectedConnections), "capacity")
+	ch <- prometheus.MustNewConstMetric(c.rejected, prometheus.CounterValue, float64(s.RejectedConnectionsPerIP), "per-ip")
+	ch <- prometheus.MustNewConstMetric(c.idleTimeouts, prometheus.CounterValue, float64(s.IdleTimeouts))
+	ch <- prometheus.MustNewConstMetric(c.writeTimeouts, prometheus.CounterValue, float64(s.WriteTimeouts))
+}
+
+// BackendCollector exports per tube statistics from a backend.
+type BackendCollector struct {
+	logger   *slog.Logger
+	provider backend.StatsProvider
+
+	jobs     *prometheus.Desc
+	waiting  *prometheus.Desc
+	puts     *prometheus.Desc
+	reserves *prometheus.Desc
+	timeouts *prometheus.Desc
+	deletes  *prometheus.Desc
+}
+
+func NewBackendCollector(logger *slog.Logger, provider backend.StatsProvider) *BackendCollector {
+	tubeDesc := func(name string, help string, labels ...string) *prometheus.Desc {
+		return prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "tube", name),
+			help,
+			append([]string{"tube"}, labels...), nil,
+		)
+	}
+
+	return &BackendCollector{
+		logger:   logger,
+		provider: provider,
+		jobs:     tubeDesc("jobs", "Number of jobs in the tube, by state.", "state"),
+		waiting:  tubeDesc("waiting", "Number of clients blocked in a reserve watching the tube."),
+		puts:     tubeDesc("puts_total", "Number of jobs put into the tube."),
+		reserves: tubeDesc("reserves_total", "Number of jobs reserved from the tube."),
+		timeouts: tubeDesc("timeouts_total", "Number of reserved jobs whose ttr expired."),
+		deletes:  tubeDesc("deletes_total", "Number of jobs deleted from the tube."),
+	}
+}
+
+func (c *BackendCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.jobs
+	ch <- c.waiting
+	ch <- c.puts
+	ch <- c.reserves
+	ch <- c.timeouts
+	ch <- c.deletes
+}
+
+func (c *BackendCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	defer cancel()
+
+	stats, err := c.provider.TubeStats(ctx)
+	if err != nil {
+		c.logger.Warn("Failed to collect tube stats", "err", err)
+
+		return
+	}
+
+	for _, t := range stats {
+		ch <- prometheus.MustNewConstMetric(c.jobs, prometheus.GaugeValue, float64(t.Ready), t.Name, "ready")
+		ch <- prometheus.MustNewConstMetric(c.jobs, prometheus.GaugeValue, float64(t.Delayed), t.Name, "delayed")
+		ch <- prometheus.MustNewConstMetric(c.jobs, prometheus.GaugeValue, float64(t.Reserved), t.Name, "reserved")
+		ch <- prometheus.MustNewConstMetric(c.jobs, prometheus.GaugeValue, float64(t.Buried), t.Name, "buried")
+		ch <- prometheus.MustNewConstMetric(c.waiting, prometheus.GaugeValue, float64(t.Waiting), t.Name)
+		ch <- prometheus.MustNewConstMetric(c.puts, prometheus.CounterValue, float64(t.TotalPuts), t.Name)
+		ch <- prometheus.MustNewConstMetric(c.reserves, prometheus.CounterValue, float64(t.TotalReserves), t.Name)
+		ch <- prometheus.MustNewConstMetric(c.timeouts, prometheus.CounterValue, float64(t.TotalTimeouts), t.Name)
+		ch <- prometheus.MustNewConstMetric(c.deletes, prometheus.CounterValue, float64(t.TotalDeletes), t.Name)
+	}
+}
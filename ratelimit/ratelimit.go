@@ -0,0 +1,284 @@
+// Package ratelimit applies token bucket limits to puts and reserves, per client or per tube.
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/csnewman/beanbridge/acl"
+	"golang.org/x/time/rate"
+)
+
+var ErrInvalidRule = errors.New("invalid rule")
+
+// Mode decides how requests exceeding a limit are handled.
+type Mode string
+
+const (
+	// ModeDelay holds the request until a token is available.
+	ModeDelay Mode = "delay"
+	// ModeReject fails the request.
+	ModeReject Mode = "reject"
+	// ModeBury accepts a put, but buries the job. Only valid for puts.
+	ModeBury Mode = "bury"
+)
+
+// Scope decides which requests share a bucket.
+type Scope string
+
+const (
+	// ScopeClient gives each principal, or each address for unauthenticated clients, its own bucket.
+	ScopeClient Scope = "client"
+	// ScopeTube gives each tube its own bucket, shared by all clients.
+	ScopeTube Scope = "tube"
+)
+
+// maxBuckets bounds the number of buckets kept before full, and so unused, buckets are dropped.
+const maxBuckets = 10000
+
+// Rule limits matching requests to Rate per second, with bursts of up to Burst. The conditions
+// match as in acl.Rule, with Actions limited to put and reserve.
+type Rule struct {
+	Actions    []acl.Action
+	Principals []string
+	Sources    []netip.Prefix
+	Tubes      []string
+	Scope      Scope
+	Rate       float64
+	Burst      int
+	Mode       Mode
+}
+
+func (r *Rule) Validate() error {
+	var errs []error
+
+	for _, a := range r.Actions {
+		if a != acl.ActionPut && a != acl.ActionReserve {
+			errs = append(errs, fmt.Errorf("%w: unsupported action %q", ErrInvalidRule, a))
+		}
+	}
+
+	switch r.Scope {
+	case ScopeClient:
+	case ScopeTube:
+		if len(r.Tubes) == 0 {
+			errs = append(errs, fmt.Errorf("%w: tube scope requires tubes", ErrInvalidRule))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("%w: unknown scope %q", ErrInvalidRule, r.Scope))
+	}
+
+	switch r.Mode {
+	case ModeDelay, ModeReject:
+	case ModeBury:
+		if len(r.Actions) == 0 || slices.Contains(r.Actions, acl.ActionReserve) {
+			errs = append(errs, fmt.Errorf("%w: bury mode only applies to put", ErrInvalidRule))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("%w: unknown mode %q", ErrInvalidRule, r.Mode))
+	}
+
+	if r.Rate <= 0 {
+		errs = append(errs, fmt.Errorf("%w: rate must be positive", ErrInvalidRule))
+	}
+
+	if r.Burst < 0 {
+		errs = append(errs, fmt.Errorf("%w: burst must not be negative", ErrInvalidRule))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (r *Rule) matches(req acl.Request, tubeRulesOnly bool) bool {
+	if tubeRulesOnly && len(r.Tubes) == 0 {
+		return false
+	}
+
+	if len(r.Tubes) > 0 && req.Tube == "" {
+		return false
+	}
+
+	filter := acl.Rule{
+		Actions:    r.Actions,
+		Principals: r.Principals,
+		Sources:    r.Sources,
+		Tubes:      r.Tubes,
+	}
+
+	return filter.Matches(req)
+}
+
+func (r *Rule) key(req acl.Request) string {
+	if r.Scope == ScopeTube {
+		return req.Tube
+	}
+
+	if req.Principal != "" {
+		return "principal:" + req.Principal
+	}
+
+	return "addr:" + req.Source.String()
+}
+
+// Decision describes how a request should be handled.
+type Decision struct {
+	// Delay is how long to wait before completing the request.
+	Delay time.Duration
+	// Reject is set if the request should fail.
+	Reject bool
+	// Bury is set if the put job should be buried.
+	Bury bool
+}
+
+type bucketKey struct {
+	rule int
+	key  string
+}
+
+type Limiter struct {
+	rules   []Rule
+	mu      sync.Mutex
+	buckets map[bucketKey]*rate.Limiter
+}
+
+func NewLimiter(rules []Rule) *Limiter {
+	return &Limiter{
+		rules:   rules,
+		buckets: make(map[bucketKey]*rate.Limiter),
+	}
+}
+
+// Take consumes a token from each rule matching the request. Rules with tube patterns only match
+// requests naming a tube, so a reserve can be checked before the tube of the job is known.
+func (l *Limiter) Take(req acl.Request) Decision {
+	return l.take(req, false)
+}
+
+// TakeTube is as Take, but only considers rules with tube patterns.
+func (l *Limiter) TakeTube(req acl.Request) Decision {
+	return l.take(req, true)
+}
+
+// CheckTube reports the decision TakeTube would make for the request, without consuming any
+// tokens. Delay is the time until every delaying rule has a token available.
+func (l *Limiter) CheckTube(req acl.Request) Decision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+
+	var d Decision
+
+	for i := range l.rules {
+		rule := &l.rules[i]
+
+		if !rule.matches(req, true) {
+			continue
+		}
+
+		b := l.bucket(i, rule.key(req))
+
+		missing := 1 - b.TokensAt(now)
+		if missing <= 0 {
+			continue
+		}
+
+		switch rule.Mode {
+		case ModeDelay:
+			d.Delay = max(d.Delay, time.Duration(missing/float64(b.Limit())*float64(time.Second)))
+		case ModeReject:
+			d.Reject = true
+		case ModeBury:
+			d.Bury = true
+		}
+	}
+
+	if d.Reject {
+		return Decision{Reject: true}
+	}
+
+	return d
+}
+
+func (l *Limiter) take(req acl.Request, tubeRulesOnly bool) Decision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+
+	var (
+		d            Decision
+		reservations []*rate.Reservation
+	)
+
+	for i := range l.rules {
+		rule := &l.rules[i]
+
+		if !rule.matches(req, tubeRulesOnly) {
+			continue
+		}
+
+		r := l.bucket(i, rule.key(req)).ReserveN(now, 1)
+
+		if delay := r.DelayFrom(now); delay > 0 {
+			switch rule.Mode {
+			case ModeDelay:
+				d.Delay = max(d.Delay, delay)
+			case ModeReject:
+				d.Reject = true
+			case ModeBury:
+				d.Bury = true
+
+				// Buried jobs do not count against the limit, so burying stops once the rate drops
+				r.CancelAt(now)
+
+				continue
+			}
+		}
+
+		reservations = append(reservations, r)
+	}
+
+	if d.Reject {
+		// Rejected requests should not count against any limit
+		for _, r := range reservations {
+			r.CancelAt(now)
+		}
+
+		return Decision{Reject: true}
+	}
+
+	return d
+}
+
+func (l *Limiter) bucket(rule int, key string) *rate.Limiter {
+	k := bucketKey{rule: rule, key: key}
+
+	if b, ok := l.buckets[k]; ok {
+		return b
+	}
+
+	if len(l.buckets) >= maxBuckets {
+		l.purge()
+	}
+
+	r := &l.rules[rule]
+
+	b := rate.NewLimiter(rate.Limit(r.Rate), max(r.Burst, 1))
+	l.buckets[k] = b
+
+	return b
+}
+
+// purge drops buckets that have refilled, as they are equivalent to new buckets.
+func (l *Limiter) purge() {
+	for k, b := range l.buckets {
+		if b.Tokens() >= float64(b.Burst()) {
+			delete(l.buckets, k)
+		}
+	}
+}
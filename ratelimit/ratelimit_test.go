@@ -0,0 +1,111 @@
+package ratelimit_test
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/csnewman/beanbridge/acl"
+	"github.com/csnewman/beanbridge/ratelimit"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiter(t *testing.T) {
+	t.Parallel()
+
+	l := ratelimit.NewLimiter([]ratelimit.Rule{
+		{
+			Actions: []acl.Action{acl.ActionPut},
+			Tubes:   []string{"emails"},
+			Scope:   ratelimit.ScopeTube,
+			Rate:    0.001,
+			Burst:   1,
+			Mode:    ratelimit.ModeBury,
+		},
+		{
+			Actions: []acl.Action{acl.ActionPut},
+			Scope:   ratelimit.ScopeClient,
+			Rate:    0.001,
+			Burst:   2,
+			Mode:    ratelimit.ModeReject,
+		},
+		{
+			Actions: []acl.Action{acl.ActionReserve},
+			Scope:   ratelimit.ScopeClient,
+			Rate:    1,
+			Burst:   1,
+			Mode:    ratelimit.ModeDelay,
+		},
+	})
+
+	a := acl.Request{Source: netip.MustParseAddr("10.0.0.1"), Action: acl.ActionPut, Tube: "emails"}
+	b := acl.Request{Source: netip.MustParseAddr("10.0.0.2"), Action: acl.ActionPut, Tube: "emails"}
+
+	require.Equal(t, ratelimit.Decision{}, l.Take(a))
+	require.Equal(t, ratelimit.Decision{Bury: true}, l.Take(a), "Tube limit should be shared")
+	require.Equal(t, ratelimit.Decision{Reject: true}, l.Take(a), "Client limit should be exceeded")
+	require.Equal(t, ratelimit.Decision{Bury: true}, l.Take(b), "Client limit should be per client")
+
+	reserve := acl.Request{Principal: "worker", Action: acl.ActionReserve}
+
+	require.Equal(t, ratelimit.Decision{}, l.Take(reserve))
+	require.Positive(t, l.Take(reserve).Delay, "Reserve should be delayed")
+	require.Equal(t, ratelimit.Decision{}, l.TakeTube(reserve), "Client rules should not apply to TakeTube")
+}
+
+func TestCheckTube(t *testing.T) {
+	t.Parallel()
+
+	l := ratelimit.NewLimiter([]ratelimit.Rule{
+		{
+			Actions: []acl.Action{acl.ActionReserve},
+			Tubes:   []string{"emails"},
+			Scope:   ratelimit.ScopeTube,
+			Rate:    0.001,
+			Burst:   1,
+			Mode:    ratelimit.ModeReject,
+		},
+		{
+			Actions: []acl.Action{acl.ActionReserve},
+			Tubes:   []string{"reports"},
+			Scope:   ratelimit.ScopeTube,
+			Rate:    1,
+			Burst:   1,
+			Mode:    ratelimit.ModeDelay,
+		},
+	})
+
+	emails := acl.Request{Principal: "worker", Action: acl.ActionReserve, Tube: "emails"}
+	reports := acl.Request{Principal: "worker", Action: acl.ActionReserve, Tube: "reports"}
+
+	require.Equal(t, ratelimit.Decision{}, l.CheckTube(emails))
+	require.Equal(t, ratelimit.Decision{}, l.CheckTube(emails), "Checking should not consume tokens")
+	require.Equal(t, ratelimit.Decision{}, l.TakeTube(emails))
+	require.Equal(t, ratelimit.Decision{Reject: true}, l.CheckTube(emails))
+
+	require.Equal(t, ratelimit.Decision{}, l.TakeTube(reports))
+
+	d := l.CheckTube(reports)
+	require.False(t, d.Reject)
+	require.Positive(t, d.Delay, "Reports should be delayed")
+	require.LessOrEqual(t, d.Delay, time.Second)
+}
+
+func TestRuleValidate(t *testing.T) {
+	t.Parallel()
+
+	valid := ratelimit.Rule{
+		Actions: []acl.Action{acl.ActionPut},
+		Scope:   ratelimit.ScopeClient,
+		Rate:    10,
+		Mode:    ratelimit.ModeBury,
+	}
+	require.NoError(t, valid.Validate())
+
+	invalid := ratelimit.Rule{
+		Actions: []acl.Action{acl.ActionDelete},
+		Scope:   ratelimit.ScopeTube,
+		Mode:    ratelimit.ModeBury,
+	}
+	require.ErrorIs(t, invalid.Validate(), ratelimit.ErrInvalidRule)
+}